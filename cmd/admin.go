@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"xixunyunsign/utils"
+)
+
+// newAdminHandler 返回守护进程的管理接口。token 不为空时要求请求携带
+// `Authorization: Bearer <token>`。
+func newAdminHandler(token string, scheduler *utils.Scheduler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /reload", func(w http.ResponseWriter, r *http.Request) {
+		result, err := scheduler.Reload()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("已通过管理接口重新加载定时任务：新增 %d，删除 %d，更新 %d，共 %d 个\n",
+			result.Added, result.Removed, result.Updated, result.Total)
+		writeJSON(w, http.StatusOK, result)
+	})
+
+	mux.HandleFunc("GET /runs", func(w http.ResponseWriter, r *http.Request) {
+		filter := utils.JobRunFilter{Outcome: r.URL.Query().Get("outcome"), Limit: 50}
+		if v := r.URL.Query().Get("schedule_id"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid schedule_id"})
+				return
+			}
+			filter.ScheduleID = id
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+				return
+			}
+			filter.Limit = limit
+		}
+		runs, err := utils.QueryJobRuns(filter)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		views := make([]jobRunView, 0, len(runs))
+		for _, run := range runs {
+			views = append(views, newJobRunView(run))
+		}
+		writeJSON(w, http.StatusOK, views)
+	})
+
+	if token == "" {
+		return mux
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// jobRunView 是管理接口返回的任务执行记录
+type jobRunView struct {
+	ID         int64     `json:"id"`
+	ScheduleID int       `json:"schedule_id"`
+	Account    string    `json:"account"`
+	Trigger    string    `json:"trigger"`
+	PlannedAt  time.Time `json:"planned_at"`
+	StartedAt  time.Time `json:"started_at"`
+	DriftMs    int64     `json:"drift_ms"`
+	DurationMs int64     `json:"duration_ms"`
+	Outcome    string    `json:"outcome"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	RetryCount int       `json:"retry_count"`
+}
+
+func newJobRunView(r utils.JobRun) jobRunView {
+	return jobRunView{
+		ID:         r.ID,
+		ScheduleID: r.ScheduleID,
+		Account:    r.Account,
+		Trigger:    r.Trigger,
+		PlannedAt:  r.PlannedAt,
+		StartedAt:  r.StartedAt,
+		DriftMs:    r.Drift().Milliseconds(),
+		DurationMs: r.Duration.Milliseconds(),
+		Outcome:    r.Outcome,
+		ErrorClass: r.ErrorClass,
+		Error:      r.Error,
+		RetryCount: r.RetryCount,
+	}
+}
+
+// startAdminServer 在后台启动管理接口
+func startAdminServer(addr, token string, scheduler *utils.Scheduler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newAdminHandler(token, scheduler),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Printf("管理接口已启动: http://%s\n", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("管理接口启动失败: %v\n", err)
+		}
+	}()
+	if token == "" {
+		log.Println("警告：管理接口未设置访问令牌，请仅监听本机地址或使用 --admin_token")
+	}
+	return srv
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
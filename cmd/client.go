@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"xixunyunsign/utils"
+	"xixunyunsign/xixunyun"
+)
+
+// newClient 创建命令行使用的习讯云客户端。
+func newClient() *xixunyun.Client {
+	return xixunyun.NewClient(xixunyun.WithBaseURL(APIBaseURL))
+}
+
+// noRelogin 为 true 时 token 失效后不自动重新登录。
+var noRelogin bool
+
+// newSession 创建绑定账号的会话，token 失效时使用数据库中保存的密码自动重新登录。
+func newSession(account string) *xixunyun.Session {
+	s := xixunyun.NewSession(newClient(), dbCredentialStore{}, account)
+	s.AutoRelogin = !noRelogin
+	s.OnRelogin = func(account string) {
+		fmt.Printf("账号 %s 的 token 已失效，已自动重新登录。\n", account)
+	}
+	return s
+}
+
+// dbCredentialStore 基于 config.db 实现 xixunyun.CredentialStore。
+type dbCredentialStore struct{}
+
+func (dbCredentialStore) Identity(account string) (xixunyun.Identity, error) {
+	return loadIdentity(account)
+}
+
+func (dbCredentialStore) Credentials(account string) (xixunyun.LoginRequest, error) {
+	password, schoolID, err := utils.GetCredentials(account)
+	if err != nil {
+		return xixunyun.LoginRequest{}, err
+	}
+	return xixunyun.LoginRequest{Account: account, Password: password, SchoolID: schoolID}, nil
+}
+
+func (dbCredentialStore) SaveToken(account string, resp *xixunyun.LoginResponse) error {
+	return utils.UpdateToken(account, resp.Token)
+}
+
+// loadIdentity 从数据库读取账号的 token 及公共参数。token 为空时由 Session 使用保存的密码重新登录。
+func loadIdentity(account string) (xixunyun.Identity, error) {
+	token, _, _, err := utils.GetUser(account)
+	if err != nil {
+		return xixunyun.Identity{}, err
+	}
+	userData, err := utils.GetAdditionalUserData(account)
+	if err != nil {
+		return xixunyun.Identity{}, fmt.Errorf("获取用户额外信息失败: %w", err)
+	}
+	return xixunyun.Identity{
+		Token:        token,
+		SchoolID:     userData["school_id"],
+		EntranceYear: userData["entrance_year"],
+		GraduateYear: userData["graduation_year"],
+	}, nil
+}
+
+// describeError 根据错误分类给出面向用户的提示。
+func describeError(err error) string {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "未找到该账号的信息，请先登录。"
+	case errors.Is(err, xixunyun.ErrNoCredentials):
+		return "登录状态已失效且未保存密码，请重新执行 login 命令登录。(" + err.Error() + ")"
+	case errors.Is(err, xixunyun.ErrTokenExpired):
+		return "登录状态已失效，请重新执行 login 命令登录。(" + err.Error() + ")"
+	case errors.Is(err, xixunyun.ErrWrongPassword):
+		return "账号或密码错误，请检查后重试。(" + err.Error() + ")"
+	case errors.Is(err, xixunyun.ErrSchoolMismatch):
+		return "学校ID与账号不匹配，请使用 search 命令确认学校ID。(" + err.Error() + ")"
+	case errors.Is(err, xixunyun.ErrOutOfRange):
+		return "签到位置不在允许范围内，请执行 query 更新经纬度或检查地址。(" + err.Error() + ")"
+	case errors.Is(err, xixunyun.ErrMaintenance):
+		return "习讯云服务器维护中，请稍后再试。(" + err.Error() + ")"
+	case errors.Is(err, xixunyun.ErrRateLimited):
+		return "请求过于频繁，请稍后再试。(" + err.Error() + ")"
+	}
+	return err.Error()
+}
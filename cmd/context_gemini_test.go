@@ -3,13 +3,13 @@ package cmd_test
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
-)
 
-var role string
+	"xixunyunsign/cmd"
+)
 
 // 定义请求中使用的结构体
 type ContentPart struct {
@@ -38,109 +38,70 @@ type RequestPayload struct {
 }
 
 func TestGenerateContent(t *testing.T) {
-	// 从环境变量中获取 API 密钥
 	apiKey := "AI*********"
-	//if apiKey == "" {
-	//	t.Fatal("未设置 GEMINI_API_KEY 环境变量")
-	//}
-	role = "********实习生"
-	// 构建请求 URL
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent?key=%s", apiKey)
-
-	// 构建请求 payload
-	payload := RequestPayload{
-		Contents: []Content{
-			{
-				Role: "user",
-				Parts: []ContentPart{
-					{Text: fmt.Sprintf("我是%s。现在要求我回答作为第一个月的实习报告月报的回复。以替换content里的内容返回给我，以api的形式返回给我，不要回复其他的任何信息，不要```json和\\n", role)},
-				},
-			},
-			{
-				Role: "user",
-				Parts: []ContentPart{
-					{Text: "[{\"title\":\"实习工作具体情况及实习任务完成情况\",\"content\":\"\",\"require\":\"1\",\"sort\":1},{\"title\":\"主要收获及工作成绩\",\"content\":\"\",\"require\":\"0\",\"sort\":2},{\"title\":\"工作中的问题及需要老师的指导帮助\",\"content\":\"\",\"require\":\"0\",\"sort\":3}]"},
-				},
-			},
-			//{
-			//	Role: "user",
-			//	Parts: []ContentPart{
-			//		{Text: "Can you describe what the magic backpack looks like?"},
-			//	},
-			//},
-		},
-	}
-
-	// 将 payload 序列化为 JSON
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		t.Fatalf("JSON 序列化失败: %v", err)
-	}
-
-	// 创建 HTTP POST 请求
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		t.Fatalf("创建 HTTP 请求失败: %v", err)
-	}
-
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-
-	// 发送请求
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		t.Fatalf("发送 HTTP 请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应体
-	bodyBytes, err := io.ReadAll(resp.Body)
+	role := "********实习生"
+	generated := `[{"title":"实习工作具体情况及实习任务完成情况","content":"完成了工作","require":"1","sort":1}]`
+
+	// 使用本地服务代替 Gemini API
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("期望 POST 请求，实际为 %s", r.Method)
+		}
+		if r.URL.Path != "/v1beta/models/gemini-1.5-flash:generateContent" {
+			t.Errorf("请求路径不正确: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("key") != apiKey {
+			t.Errorf("未携带 API 密钥: %s", r.URL.RawQuery)
+		}
+
+		bodyBytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("读取请求体失败: %v", err)
+		}
+		var payload RequestPayload
+		if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+			t.Fatalf("解析请求 JSON 失败: %v", err)
+		}
+		if len(payload.Contents) != 2 || !contains(payload.Contents[0].Parts[0].Text, role) {
+			t.Errorf("请求内容不包含工作角色: %s", string(bodyBytes))
+		}
+
+		resp := Response{
+			Candidates: []Candidate{{
+				Content:      Content{Role: "model", Parts: []ContentPart{{Text: generated}}},
+				FinishReason: "STOP",
+			}},
+			ModelVersion: "gemini-1.5-flash",
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+	cmd.GeminiBaseURL = ts.URL
+
+	generatedText, err := cmd.GenerateContent(role, apiKey)
 	if err != nil {
-		t.Fatalf("读取响应体失败: %v", err)
+		t.Fatalf("生成内容失败: %v", err)
 	}
 
-	// 打印响应的所有内容
-	t.Logf("响应状态码: %d", resp.StatusCode)
-	t.Logf("响应体: %s", string(bodyBytes))
-
-	// 检查响应状态码
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("HTTP 请求失败，状态码: %s，响应体: %s", resp.Status, string(bodyBytes))
-	}
-
-	// 解析响应 JSON
-	var responseData Response
-	if err := json.Unmarshal(bodyBytes, &responseData); err != nil {
-		t.Fatalf("解析响应 JSON 失败: %v", err)
-	}
-
-	// 检查是否有候选内容
-	if len(responseData.Candidates) == 0 {
-		t.Fatal("响应中不包含任何候选内容")
-	}
-
-	// 获取第一个候选的文本内容
-	firstCandidate := responseData.Candidates[0]
-	if len(firstCandidate.Content.Parts) == 0 {
-		t.Fatal("第一个候选内容中不包含任何部分")
-	}
-
-	generatedText := firstCandidate.Content.Parts[0].Text
-
 	// 打印生成的文本内容
 	t.Logf("生成的文本内容: %s", generatedText)
 
-	// 断言生成的文本不为空
-	if generatedText == "" {
-		t.Error("生成的文本内容为空")
+	if generatedText != generated {
+		t.Errorf("生成的文本内容不符合预期: %s", generatedText)
 	}
+}
+
+func TestGenerateContent_HTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"message":"API key not valid"}}`))
+	}))
+	defer ts.Close()
+	cmd.GeminiBaseURL = ts.URL
 
-	//// 可根据需要添加更多断言，例如检查文本是否包含特定关键词
-	//expectedSubstring := "AI works"
-	//if !contains(generatedText, expectedSubstring) {
-	//	t.Errorf("生成的文本不包含预期的子字符串: %s", expectedSubstring)
-	//}
+	if _, err := cmd.GenerateContent("实习生", "bad-key"); err == nil {
+		t.Fatal("期望返回错误，实际为 nil")
+	}
 }
 
 // contains 是一个简单的子字符串检查函数
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/notify"
+	"xixunyunsign/utils"
+)
+
+var (
+	drainTimeout   time.Duration
+	reloadInterval time.Duration
+	adminAddr      string
+	adminToken     string
+	catchUpGrace   time.Duration
+	retryInterval  time.Duration
+	leaseTTL       time.Duration
+	instanceID     string
+)
+
+// DaemonCmd 以守护进程方式运行定时签到
+var DaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "以守护进程方式运行定时任务(签到)",
+	Run: func(cmd *cobra.Command, args []string) {
+		runDaemon()
+	},
+}
+
+func init() {
+	DaemonCmd.Flags().DurationVar(&drainTimeout, "drain_timeout", 2*time.Minute, "收到停止信号后等待执行中任务完成的最长时间")
+	DaemonCmd.Flags().DurationVar(&reloadInterval, "reload_interval", 30*time.Second, "检查定时任务是否变更的间隔(0 为不检查，仍可通过 SIGHUP 重新加载)")
+	DaemonCmd.Flags().StringVar(&adminAddr, "admin_addr", "", "管理接口监听地址(如 127.0.0.1:8620，为空时不启用)")
+	DaemonCmd.Flags().StringVar(&adminToken, "admin_token", os.Getenv("XIXUN_ADMIN_TOKEN"), "管理接口的访问令牌(也可通过环境变量 XIXUN_ADMIN_TOKEN 设置)")
+	DaemonCmd.Flags().DurationVar(&catchUpGrace, "catchup_grace", 3*time.Hour, "启动时检查多长时间内错过的执行(0 为不检查)，按任务的 missed_policy 处理")
+	DaemonCmd.Flags().StringVarP(&secret_key, "secret_key", "k", "", "server酱密钥(用于 notify-only 策略的错过执行通知)")
+	DaemonCmd.Flags().BoolVar(&noRelogin, "no_relogin", false, "token 失效时不使用已保存的密码自动重新登录")
+	DaemonCmd.Flags().DurationVar(&retryInterval, "retry_interval", 30*time.Second, "检查重试队列的间隔")
+	DaemonCmd.Flags().DurationVar(&leaseTTL, "lease_ttl", 30*time.Second, "多个实例共用数据库时主节点租约的有效期，超过该时间未续约由其他实例接管(0 为不使用租约)")
+	DaemonCmd.Flags().StringVar(&instanceID, "instance_id", defaultInstanceID(), "本实例的唯一标识(默认为主机名-进程号)")
+	addRetryFlags(DaemonCmd)
+}
+
+// defaultInstanceID 返回默认的实例标识
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "xixun"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+func runDaemon() {
+	loc, err := location()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	opts := []utils.SchedulerOption{utils.WithLocation(loc), utils.WithCatchUp(catchUpGrace, notifyMissed)}
+	if leaseTTL > 0 {
+		opts = append(opts, utils.WithLease(instanceID, leaseTTL))
+	}
+	scheduler, err := utils.InitScheduler(jobHandlers(), opts...)
+	if err != nil {
+		log.Printf("初始化定时任务调度器失败: %v\n", err)
+		return
+	}
+	log.Printf("调度器已启动(时区 %s)，共加载 %d 个定时任务\n", loc, scheduler.Len())
+
+	if adminAddr != "" {
+		admin := startAdminServer(adminAddr, adminToken, scheduler)
+		defer admin.Close()
+	}
+
+	// 设置优雅关闭信号
+	stopChan := make(chan os.Signal, 1)
+	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stopChan)
+
+	// SIGHUP 立即重新加载定时任务
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	defer signal.Stop(hupChan)
+
+	// 定期处理重试队列
+	retryCtx, cancelRetries := context.WithCancel(context.Background())
+	defer cancelRetries()
+	retries := &retryWorker{ctx: retryCtx}
+	retryTicker := time.NewTicker(retryInterval)
+	defer retryTicker.Stop()
+	// 重试队列与定时任务一样只由主节点处理
+	if scheduler.IsLeader() {
+		retries.trigger()
+	}
+
+	// 定期检查 schedules 表是否有变更
+	var tick <-chan time.Time
+	if reloadInterval > 0 {
+		ticker := time.NewTicker(reloadInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// 等待中断信号
+wait:
+	for {
+		select {
+		case <-stopChan:
+			break wait
+		case <-retryTicker.C:
+			if scheduler.IsLeader() {
+				retries.trigger()
+			}
+		case <-hupChan:
+			logReload("SIGHUP", scheduler.Reload)
+		case <-tick:
+			result, reloaded, err := scheduler.ReloadIfChanged()
+			if err != nil {
+				log.Printf("检查定时任务变更失败: %v\n", err)
+			} else if reloaded && result.Changed() {
+				log.Printf("检测到定时任务变更，已重新加载：新增 %d，删除 %d，更新 %d，共 %d 个\n",
+					result.Added, result.Removed, result.Updated, result.Total)
+			}
+		}
+	}
+	log.Println("收到停止信号，正在等待执行中的任务完成...")
+
+	// 停止调度器和重试队列，不再触发新任务
+	retryTicker.Stop()
+	drained := scheduler.Stop()
+	done := make(chan struct{})
+	go func() {
+		<-drained.Done()
+		retries.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Println("调度器已停止，程序退出")
+	case <-time.After(drainTimeout):
+		log.Println("等待超时，取消未完成的任务")
+		scheduler.Cancel()
+		cancelRetries()
+	case <-stopChan:
+		log.Println("再次收到停止信号，取消未完成的任务")
+		scheduler.Cancel()
+		cancelRetries()
+	}
+}
+
+// notifyMissed 实现 utils.MissedFunc，通知用户定时任务错过了执行。
+func notifyMissed(t utils.ScheduleTask, missedAt time.Time) {
+	sendNotify(context.Background(), secret_key, notify.Message{
+		Event:      notify.EventMissedRun,
+		Account:    t.Account,
+		ScheduleID: t.ID,
+		Data:       notify.Data{"missed_at": missedAt},
+	})
+}
+
+// logReload 执行一次重新加载并记录结果
+func logReload(source string, reload func() (utils.ReloadResult, error)) {
+	result, err := reload()
+	if err != nil {
+		log.Printf("重新加载定时任务失败(%s): %v\n", source, err)
+		return
+	}
+	log.Printf("已重新加载定时任务(%s)：新增 %d，删除 %d，更新 %d，共 %d 个\n",
+		source, result.Added, result.Removed, result.Updated, result.Total)
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/utils"
+)
+
+var (
+	keyFile    string
+	newKeyFile string
+)
+
+// AddDatabaseFlags 在根命令上注册 --stateless 全局参数，并在解析参数后、执行命令前打开数据库。
+func AddDatabaseFlags(root *cobra.Command) {
+	root.PersistentFlags().BoolVar(&utils.Stateless, "stateless", utils.Stateless, "使用内存数据库，不读写 config.db(环境变量 XIXUN_STATELESS)")
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := utils.InitDB(); err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("数据库初始化失败: %w", err)
+		}
+		return nil
+	}
+}
+
+// DBCmd 管理本地数据库
+var DBCmd = &cobra.Command{
+	Use:   "db",
+	Short: "管理本地数据库(结构升级、加密、解密、更换主密码)",
+}
+
+var dbEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "加密数据库中保存的密码与 token",
+	Run: func(cmd *cobra.Command, args []string) {
+		passphrase, err := resolvePassphrase(keyFile, utils.EnvMasterKey)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := utils.EncryptDatabase(passphrase); err != nil {
+			fmt.Println("加密数据库失败:", err)
+			return
+		}
+		fmt.Println("数据库已加密，之后运行命令时请通过环境变量提供主密码。")
+	},
+}
+
+var dbDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "解密数据库，将密码与 token 还原为明文",
+	Run: func(cmd *cobra.Command, args []string) {
+		passphrase, err := resolvePassphrase(keyFile, utils.EnvMasterKey)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := utils.DecryptDatabase(passphrase); err != nil {
+			fmt.Println("解密数据库失败:", err)
+			return
+		}
+		fmt.Println("数据库已解密。")
+	},
+}
+
+var dbRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "更换数据库主密码",
+	Run: func(cmd *cobra.Command, args []string) {
+		oldPassphrase, err := resolvePassphrase(keyFile, utils.EnvMasterKey)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		newPassphrase, err := resolvePassphrase(newKeyFile, "XIXUN_NEW_MASTER_KEY")
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := utils.RekeyDatabase(oldPassphrase, newPassphrase); err != nil {
+			fmt.Println("更换主密码失败:", err)
+			return
+		}
+		fmt.Println("主密码已更换，请同步更新环境变量或密钥文件。")
+	},
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "将数据库结构升级到最新版本",
+	Run: func(cmd *cobra.Command, args []string) {
+		applied, err := utils.Migrate()
+		if err != nil {
+			fmt.Println("升级数据库失败:", err)
+			return
+		}
+		if len(applied) == 0 {
+			fmt.Printf("数据库已是最新版本(%d)。\n", utils.LatestSchemaVersion())
+			return
+		}
+		fmt.Printf("已应用 %d 个迁移，当前版本 %d。\n", len(applied), applied[len(applied)-1])
+	},
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "查看数据库结构版本与迁移状态",
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := utils.SchemaVersion()
+		if err != nil {
+			fmt.Println("查询数据库版本失败:", err)
+			return
+		}
+		statuses, err := utils.MigrationStatuses()
+		if err != nil {
+			fmt.Println("查询迁移状态失败:", err)
+			return
+		}
+		encrypted, err := utils.IsDatabaseEncrypted()
+		if err != nil {
+			encrypted = false
+		}
+
+		fmt.Printf("数据库文件: %s\n", utils.DBPath)
+		fmt.Printf("当前版本: %d，程序支持的最新版本: %d，加密: %v\n", version, utils.LatestSchemaVersion(), encrypted)
+		for _, st := range statuses {
+			state := "待应用"
+			if st.Applied {
+				state = "已应用 " + st.AppliedAt
+			}
+			fmt.Printf("  %3d  %-40s %s\n", st.Version, st.Name, state)
+		}
+	},
+}
+
+func init() {
+	DBCmd.PersistentFlags().StringVar(&keyFile, "key_file", "", "主密码文件(默认读取环境变量 XIXUN_MASTER_KEY 或 XIXUN_MASTER_KEY_FILE)")
+	dbRekeyCmd.Flags().StringVar(&newKeyFile, "new_key_file", "", "新主密码文件(默认读取环境变量 XIXUN_NEW_MASTER_KEY)")
+
+	DBCmd.AddCommand(dbEncryptCmd)
+	DBCmd.AddCommand(dbDecryptCmd)
+	DBCmd.AddCommand(dbRekeyCmd)
+	DBCmd.AddCommand(dbMigrateCmd)
+	DBCmd.AddCommand(dbStatusCmd)
+}
+
+// resolvePassphrase 按 密钥文件参数 > 环境变量 的顺序读取主密码。
+func resolvePassphrase(file, env string) (string, error) {
+	if file != "" {
+		return utils.ReadKeyFile(file)
+	}
+	var passphrase string
+	if env == utils.EnvMasterKey {
+		var err error
+		if passphrase, err = utils.MasterPassphrase(); err != nil {
+			return "", err
+		}
+	} else {
+		passphrase = os.Getenv(env)
+	}
+	if passphrase == "" {
+		return "", errors.New("未提供主密码，请设置环境变量 " + env + " 或使用密钥文件参数")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/xixunyun"
+)
+
+// 外部服务地址。默认值可通过环境变量覆盖，嵌入本工具的程序也可以直接修改这些变量，
+// 测试时将其指向 httptest 服务即可完全离线运行。
+var (
+	// APIBaseURL 习讯云 API 地址（登录、签到、上传、报告、学校列表）。
+	APIBaseURL = envOrDefault("XIXUN_API_BASE", xixunyun.DefaultBaseURL)
+	// GeminiBaseURL Gemini 生成式语言 API 地址。
+	GeminiBaseURL = envOrDefault("XIXUN_GEMINI_BASE", "https://generativelanguage.googleapis.com")
+	// ServerChanBaseURL Server酱推送 API 地址。
+	ServerChanBaseURL = envOrDefault("XIXUN_SERVERCHAN_BASE", "https://sctapi.ftqq.com")
+)
+
+// AddEndpointFlags 在根命令上注册覆盖外部服务地址的全局参数。
+func AddEndpointFlags(root *cobra.Command) {
+	root.PersistentFlags().StringVar(&APIBaseURL, "api_base", APIBaseURL, "习讯云 API 地址(环境变量 XIXUN_API_BASE)")
+	root.PersistentFlags().StringVar(&GeminiBaseURL, "gemini_base", GeminiBaseURL, "Gemini API 地址(环境变量 XIXUN_GEMINI_BASE)")
+	root.PersistentFlags().StringVar(&ServerChanBaseURL, "serverchan_base", ServerChanBaseURL, "Server酱 API 地址(环境变量 XIXUN_SERVERCHAN_BASE)")
+}
+
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -43,7 +45,8 @@ func TestMonthReportUploadSelectFile(t *testing.T) {
 	defer ts.Close()
 
 	// Test function
-	uri := cmd.MonthReportUploadSelectFile("dummy_file_path", "dummy_token")
+	cmd.APIBaseURL = ts.URL
+	uri := cmd.MonthReportUploadSelectFile(writeDummyFile(t), "dummy_token")
 	assert.Equal(t, expectedURI, uri)
 }
 
@@ -62,7 +65,8 @@ func TestMonthReportUploadSelectFile_Error(t *testing.T) {
 	defer ts.Close()
 
 	// Test function with error
-	uri := cmd.MonthReportUploadSelectFile("dummy_file_path", "dummy_token")
+	cmd.APIBaseURL = ts.URL
+	uri := cmd.MonthReportUploadSelectFile(writeDummyFile(t), "dummy_token")
 	assert.Empty(t, uri)
 }
 
@@ -78,9 +82,20 @@ func TestMonthReportUploadSelectFile_InvalidJSON(t *testing.T) {
 	defer ts.Close()
 
 	// Test function with invalid JSON response
-	uri := cmd.MonthReportUploadSelectFile("dummy_file_path", "dummy_token")
+	cmd.APIBaseURL = ts.URL
+	uri := cmd.MonthReportUploadSelectFile(writeDummyFile(t), "dummy_token")
 	assert.Empty(t, uri)
 }
+
+// writeDummyFile 在临时目录中创建一个待上传的文件
+func writeDummyFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "dummy.jpg")
+	if err := os.WriteFile(path, []byte("dummy image"), 0o600); err != nil {
+		t.Fatalf("Failed to write dummy file: %v", err)
+	}
+	return path
+}
+
 func TestPostRequest(t *testing.T) {
 	// Create a test server to mock the API endpoint
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/utils"
+)
+
+var (
+	historyFrom   string
+	historyTo     string
+	historyStatus string
+	historyLimit  int
+)
+
+// HistoryCmd 查询签到记录
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "查询签到记录",
+	Run: func(cmd *cobra.Command, args []string) {
+		showHistory()
+	},
+}
+
+func init() {
+	HistoryCmd.Flags().StringVarP(&account, "account", "a", "", "账号(为空时查询全部账号)")
+	HistoryCmd.Flags().StringVarP(&historyFrom, "from", "f", "", "开始日期(格式为2006-01-02，包含当天)")
+	HistoryCmd.Flags().StringVarP(&historyTo, "to", "t", "", "结束日期(格式为2006-01-02，包含当天)")
+	HistoryCmd.Flags().StringVarP(&historyStatus, "status", "s", "", "签到状态(success/failed/already_signed)")
+	HistoryCmd.Flags().IntVarP(&historyLimit, "limit", "n", 50, "最多显示条数(0 为不限制)")
+}
+
+func showHistory() {
+	filter := utils.SignLogFilter{Account: account, Status: historyStatus, Limit: historyLimit}
+	switch historyStatus {
+	case "", utils.SignStatusSuccess, utils.SignStatusFailed, utils.SignStatusAlreadySigned:
+	default:
+		fmt.Println("签到状态只能为 success、failed 或 already_signed")
+		return
+	}
+	if historyFrom != "" {
+		from, err := time.ParseInLocation("2006-01-02", historyFrom, time.Local)
+		if err != nil {
+			fmt.Println("开始日期格式不正确:", err)
+			return
+		}
+		filter.From = from
+	}
+	if historyTo != "" {
+		to, err := time.ParseInLocation("2006-01-02", historyTo, time.Local)
+		if err != nil {
+			fmt.Println("结束日期格式不正确:", err)
+			return
+		}
+		filter.To = to.AddDate(0, 0, 1)
+	}
+
+	logs, err := utils.QuerySignLogs(filter)
+	if err != nil {
+		fmt.Println("查询签到记录失败:", err)
+		return
+	}
+	if len(logs) == 0 {
+		fmt.Println("没有符合条件的签到记录")
+		return
+	}
+
+	for _, l := range logs {
+		fmt.Printf("[%s] %-12s %-7s 来源:%-8s 耗时:%-6s code:%-6d 坐标:%s,%s 地址:%s\n",
+			l.SignedAt.Local().Format("2006-01-02 15:04:05"), l.Account, l.Status, l.Trigger,
+			l.Duration.Round(time.Millisecond), l.Code, l.Latitude, l.Longitude, l.Address)
+		if l.Message != "" {
+			fmt.Printf("    %s", l.Message)
+			if l.ErrorKind != "" {
+				fmt.Printf(" (%s)", l.ErrorKind)
+			}
+			fmt.Println()
+		}
+	}
+}
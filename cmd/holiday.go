@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/utils"
+)
+
+var (
+	calendarName    string
+	calendarYear    int
+	calendarReplace bool
+)
+
+// HolidayCmd 管理节假日日历
+var HolidayCmd = &cobra.Command{
+	Use:   "holiday",
+	Short: "管理节假日与调休日历(用于定时任务只在工作日签到)",
+}
+
+var holidayImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "从 JSON 或 ICS 文件导入日历",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		importCalendar(args[0])
+	},
+}
+
+var holidayListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "查看日历中的放假与调休上班日期",
+	Run: func(cmd *cobra.Command, args []string) {
+		listCalendar()
+	},
+}
+
+var holidayCheckCmd = &cobra.Command{
+	Use:   "check [date]",
+	Short: "查看某天(默认今天)是否为工作日",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		loc, err := location()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		day := time.Now().In(loc)
+		if len(args) == 1 {
+			if day, err = time.ParseInLocation("2006-01-02", args[0], loc); err != nil {
+				fmt.Println("日期格式不正确:", err)
+				return
+			}
+		}
+		workday, reason, err := utils.IsWorkday(calendarName, day)
+		if err != nil {
+			fmt.Println("查询日历失败:", err)
+			return
+		}
+		state := "休息日"
+		if workday {
+			state = "工作日"
+		}
+		fmt.Printf("%s 是%s(%s)\n", day.Format("2006-01-02"), state, reason)
+	},
+}
+
+func init() {
+	HolidayCmd.PersistentFlags().StringVar(&calendarName, "calendar", utils.BuiltinCalendar, "日历名称(内置日历为 cn)")
+	holidayImportCmd.Flags().BoolVar(&calendarReplace, "replace", false, "导入前清空该日历中已导入的记录")
+	holidayListCmd.Flags().IntVar(&calendarYear, "year", time.Now().Year(), "年份(0 为全部)")
+
+	HolidayCmd.AddCommand(holidayImportCmd)
+	HolidayCmd.AddCommand(holidayListCmd)
+	HolidayCmd.AddCommand(holidayCheckCmd)
+}
+
+func importCalendar(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Println("打开日历文件失败:", err)
+		return
+	}
+	defer f.Close()
+
+	var days []utils.CalendarDay
+	if strings.EqualFold(filepath.Ext(path), ".ics") {
+		days, err = utils.ParseCalendarICS(f)
+	} else {
+		var file utils.CalendarFile
+		file, err = utils.ParseCalendarJSON(f)
+		days = file.Days
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	n, err := utils.ImportCalendar(calendarName, days, calendarReplace)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("已向日历 %s 导入 %d 天\n", calendarName, n)
+}
+
+func listCalendar() {
+	days, err := utils.CalendarDays(calendarName, calendarYear)
+	if err != nil {
+		fmt.Println("查询日历失败:", err)
+		return
+	}
+	if len(days) == 0 {
+		fmt.Printf("日历 %s 中没有记录\n", calendarName)
+		return
+	}
+	for _, d := range days {
+		state := "放假"
+		if d.Type == utils.DayTypeWorkday {
+			state = "上班"
+		}
+		fmt.Printf("%s %s %s\n", d.Date, state, d.Name)
+	}
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"xixunyunsign/utils"
+	"xixunyunsign/xixunyun"
+)
+
+// jobHandlers 返回各类型定时任务的处理函数，守护进程、重试队列与 schedule run 共用。
+func jobHandlers() utils.JobHandlers {
+	return utils.JobHandlers{
+		utils.JobTypeSign:          runSignJob,
+		utils.JobTypeQuery:         runQueryJob,
+		utils.JobTypeTokenRefresh:  runTokenRefreshJob,
+		utils.JobTypeReport:        runReportJob,
+		utils.JobTypeSchoolRefresh: runSchoolRefreshJob,
+		utils.JobTypeSummary:       runSummaryJob,
+		utils.JobTypeReportComment: runReportCommentJob,
+	}
+}
+
+// signTrigger 将任务的触发方式转换为签到记录中的触发来源
+func signTrigger(jobTrigger string) string {
+	switch jobTrigger {
+	case utils.JobTriggerManual:
+		return utils.SignTriggerManual
+	case utils.JobTriggerRetry:
+		return utils.SignTriggerRetry
+	}
+	return utils.SignTriggerSchedule
+}
+
+// runSignJob 执行签到任务。按计划执行时遇到临时性错误会加入重试队列，今日已签到不算失败。
+func runSignJob(ctx context.Context, job utils.Job) error {
+	var payload utils.SignPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	used, resp, err := performSign(ctx, newSignParams(job.Account, payload), signTrigger(job.Trigger))
+	if errors.Is(err, xixunyun.ErrAlreadySigned) {
+		log.Printf("账号 %s 今日已签到，跳过\n", job.Account)
+		return nil
+	}
+	// 重试任务的结果由重试队列统一通知
+	retrying := job.Trigger == utils.JobTriggerRetry
+	if err != nil {
+		if job.Trigger == utils.JobTriggerSchedule || job.Trigger == utils.JobTriggerCatchUp {
+			if item, handled := enqueueSignRetry(used, job.ScheduleID, err); handled {
+				if item.Status == utils.RetryStatusPending {
+					log.Printf("账号 %s 签到失败，已加入重试队列(ID %d)，将于 %s 重试\n", job.Account, item.ID, item.NextAttemptAt.Local().Format("15:04:05"))
+				}
+				retrying = true
+			}
+		}
+		if !retrying {
+			msg := signFailureMessage(used, err, "")
+			msg.ScheduleID = job.ScheduleID
+			sendNotify(ctx, secret_key, msg)
+		}
+		return err
+	}
+	log.Printf("账号 %s 签到成功，坐标：%s,%s\n", job.Account, used.Latitude, used.Longitude)
+	if !retrying {
+		msg := signSuccessMessage(used, resp)
+		msg.ScheduleID = job.ScheduleID
+		sendNotify(ctx, secret_key, msg)
+	}
+	return nil
+}
+
+// runQueryJob 查询签到信息并更新数据库中的应签到坐标
+func runQueryJob(ctx context.Context, job utils.Job) error {
+	lat, lng, err := refreshCoordinates(ctx, job.Account)
+	if err != nil {
+		return err
+	}
+	log.Printf("账号 %s 的应签到坐标已更新为 %s,%s\n", job.Account, lat, lng)
+	return nil
+}
+
+// runTokenRefreshJob 使用保存的密码重新登录，避免 token 过期后签到时才发现
+func runTokenRefreshJob(ctx context.Context, job utils.Job) error {
+	s := newSession(job.Account)
+	s.OnRelogin = nil
+	if _, err := s.Relogin(ctx); err != nil {
+		return err
+	}
+	log.Printf("账号 %s 已重新登录，token 已刷新\n", job.Account)
+	return nil
+}
+
+// runReportJob 提交执行时间所在日、周或月的报告
+func runReportJob(ctx context.Context, job utils.Job) error {
+	var payload utils.ReportPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	if err := validateReportPayload(payload); err != nil {
+		return err
+	}
+	content := payload.Content
+	if payload.ContentFile != "" {
+		data, err := os.ReadFile(payload.ContentFile)
+		if err != nil {
+			return fmt.Errorf("读取报告内容失败: %w", err)
+		}
+		content = string(data)
+	}
+
+	at := job.PlannedAt
+	if at.IsZero() {
+		loc, err := location()
+		if err != nil {
+			return err
+		}
+		at = time.Now().In(loc)
+	}
+	start, end := reportPeriod(payload.BusinessType, at)
+	resp, err := newSession(job.Account).SubmitReport(ctx, xixunyun.ReportRequest{
+		BusinessType: payload.BusinessType,
+		StartDate:    start,
+		EndDate:      end,
+		Content:      content,
+		Attachment:   payload.Attachment,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("账号 %s 的报告(%s %s - %s)已提交: %s\n", job.Account, payload.BusinessType, start, end, resp.Message)
+	msg := reportSubmittedMessage(job.Account, payload.BusinessType, start, end, resp.Message)
+	msg.ScheduleID = job.ScheduleID
+	sendNotify(ctx, secret_key, msg)
+	return nil
+}
+
+// runSchoolRefreshJob 重新获取并保存学校列表
+func runSchoolRefreshJob(ctx context.Context, job utils.Job) error {
+	if err := utils.FetchAndSaveSchoolData(newClient()); err != nil {
+		return err
+	}
+	log.Println("学校列表已更新")
+	return nil
+}
+
+// runSummaryJob 发送执行日前一天所在月份的考勤汇总，每月 1 日执行时即为上个月的汇总
+func runSummaryJob(ctx context.Context, job utils.Job) error {
+	at := job.PlannedAt
+	if at.IsZero() {
+		loc, err := location()
+		if err != nil {
+			return err
+		}
+		at = time.Now().In(loc)
+	}
+	month := at.AddDate(0, 0, -1)
+	days, err := utils.MonthlyAttendance(job.Account, month)
+	if err != nil {
+		return err
+	}
+	msg := attendanceSummaryMessage(job.Account, month, days)
+	msg.ScheduleID = job.ScheduleID
+	sendNotify(ctx, secret_key, msg)
+	log.Printf("账号 %s 的 %s 考勤汇总已发送\n", job.Account, month.Format("2006-01"))
+	return nil
+}
+
+// runReportCommentJob 查询已提交报告的批阅，对新的或修改过的批阅发送通知。
+// 第一次执行时只记录现有的批阅，不发送通知。
+func runReportCommentJob(ctx context.Context, job utils.Job) error {
+	reports, err := newSession(job.Account).ListReports(ctx, xixunyun.ReportListRequest{})
+	if err != nil {
+		return err
+	}
+	seen, ok, err := utils.SeenReportComments(job.Account)
+	if err != nil {
+		return err
+	}
+	sent := 0
+	for _, r := range reports {
+		id := r.ID.String()
+		if r.Comment == "" || seen[id] == r.Comment {
+			continue
+		}
+		seen[id] = r.Comment
+		if !ok {
+			continue
+		}
+		msg := reportCommentedMessage(job.Account, r)
+		msg.ScheduleID = job.ScheduleID
+		sendNotify(ctx, secret_key, msg)
+		sent++
+	}
+	if err := utils.SaveSeenReportComments(job.Account, seen); err != nil {
+		return err
+	}
+	if !ok {
+		log.Printf("账号 %s 首次查询报告批阅，已记录现有的 %d 条批阅\n", job.Account, len(seen))
+	} else {
+		log.Printf("账号 %s 收到 %d 条新的报告批阅\n", job.Account, sent)
+	}
+	return nil
+}
+
+// validateReportPayload 校验报告任务的参数
+func validateReportPayload(p utils.ReportPayload) error {
+	switch p.BusinessType {
+	case "day", "week", "month":
+	default:
+		return fmt.Errorf("不支持的报告类型: %q(应为 day、week 或 month)", p.BusinessType)
+	}
+	if p.Content == "" && p.ContentFile == "" {
+		return errors.New("报告内容为空，请提供 --content 或 --content_file")
+	}
+	return nil
+}
+
+// reportPeriod 返回 at 所在的日、周(周一至周日)或月的起止日期，格式为 2006/01/02
+func reportPeriod(businessType string, at time.Time) (string, string) {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	start, end := day, day
+	switch businessType {
+	case "week":
+		start = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		end = start.AddDate(0, 0, 6)
+	case "month":
+		start = day.AddDate(0, 0, 1-day.Day())
+		end = start.AddDate(0, 1, -1)
+	}
+	return start.Format("2006/01/02"), end.Format("2006/01/02")
+}
@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/notify"
+	"xixunyunsign/utils"
+	"xixunyunsign/xixunyun"
+)
+
+// secret_key 是命令行 -k 指定的 Server酱 SendKey，与数据库中保存的通知渠道同时生效
+var secret_key string
+
+// EnvNotifyURLs 是以空白字符分隔的通知 URL，对所有账号生效，适合在 CI 中通过 Secrets 配置
+const EnvNotifyURLs = "XIXUN_NOTIFY_URLS"
+
+// newDispatcher 返回发送账号通知的 Dispatcher，包括 key 不为空时的 Server酱渠道、XIXUN_NOTIFY_URLS 中的渠道，
+// 以及数据库中账号(及所有账号)与定时任务 scheduleID 的已启用渠道。
+func newDispatcher(acct string, scheduleID int, key string) *notify.Dispatcher {
+	d := &notify.Dispatcher{}
+	if key != "" {
+		d.Add("serverchan(-k)", &notify.ServerChan{SendKey: key, BaseURL: ServerChanBaseURL})
+	}
+	for i, raw := range notify.SplitURLs(os.Getenv(EnvNotifyURLs)) {
+		kind, cfg, err := notify.ParseURL(raw)
+		if err == nil {
+			var n notify.Notifier
+			if n, err = newNotifier(utils.NotifyChannel{Kind: kind, Config: cfg}); err == nil {
+				d.Add(fmt.Sprintf("%s(%s 第 %d 个)", kind, EnvNotifyURLs, i+1), n)
+				continue
+			}
+		}
+		log.Printf("%s 中第 %d 个通知 URL 有误: %v\n", EnvNotifyURLs, i+1, err)
+	}
+	if utils.Stateless {
+		return d
+	}
+	channels, err := utils.NotifyChannelsFor(acct, scheduleID)
+	if err != nil {
+		log.Printf("读取通知渠道失败: %v\n", err)
+		return d
+	}
+	for _, c := range channels {
+		n, err := newNotifier(c)
+		if err != nil {
+			log.Printf("通知渠道[%d]配置有误: %v\n", c.ID, err)
+			continue
+		}
+		d.Add(fmt.Sprintf("%s[%d]", c.Kind, c.ID), n)
+	}
+	return d
+}
+
+// newNotifier 根据保存的配置创建通知渠道，Server酱未指定 base_url 时使用 XIXUN_SERVERCHAN_BASE
+func newNotifier(c utils.NotifyChannel) (notify.Notifier, error) {
+	cfg := notify.Config{}
+	for k, v := range c.Config {
+		cfg[k] = v
+	}
+	if c.Kind == "serverchan" && cfg["base_url"] == "" {
+		cfg["base_url"] = ServerChanBaseURL
+	}
+	return notify.New(c.Kind, cfg)
+}
+
+// sendNotify 使用通知模板生成通知并发送到账号的所有渠道，发送失败只记录日志
+func sendNotify(ctx context.Context, key string, msg notify.Message) {
+	d := newDispatcher(msg.Account, msg.ScheduleID, key)
+	if d.Len() == 0 {
+		return
+	}
+	if err := d.Send(ctx, renderMessage(msg)); err != nil {
+		log.Printf("发送通知失败: %v\n", err)
+	}
+}
+
+// signSuccessMessage 构造签到成功的通知
+func signSuccessMessage(used signParams, resp *xixunyun.SignInResponse) notify.Message {
+	return notify.Message{
+		Event:   notify.EventSignSuccess,
+		Account: used.Account,
+		Data: notify.Data{
+			"message":   resp.Message,
+			"address":   used.Address,
+			"latitude":  used.Latitude,
+			"longitude": used.Longitude,
+		},
+	}
+}
+
+// signFailureMessage 构造签到失败的通知，登录状态失效且无法自动重新登录时使用 token_expired 事件。
+// retryNote 为加入重试队列的说明，没有时为空。
+func signFailureMessage(used signParams, err error, retryNote string) notify.Message {
+	msg := notify.Message{
+		Event:   notify.EventSignFailure,
+		Account: used.Account,
+		Data: notify.Data{
+			"message":    err.Error(),
+			"address":    used.Address,
+			"retry_note": retryNote,
+		},
+	}
+	var apiErr *xixunyun.APIError
+	if errors.As(err, &apiErr) {
+		msg.Data["code"], msg.Data["message"] = apiErr.Code, apiErr.Message
+	}
+	if errors.Is(err, xixunyun.ErrTokenExpired) || errors.Is(err, xixunyun.ErrNoCredentials) {
+		msg.Event = notify.EventTokenExpired
+	}
+	return msg
+}
+
+// reportNames 是报告类型对应的名称
+var reportNames = map[string]string{"day": "日报", "week": "周报", "month": "月报"}
+
+// reportSubmittedMessage 构造报告已提交的通知
+func reportSubmittedMessage(acct, businessType, start, end, message string) notify.Message {
+	name, ok := reportNames[businessType]
+	if !ok {
+		name = "报告"
+	}
+	return notify.Message{
+		Event:   notify.EventReportSubmitted,
+		Account: acct,
+		Data: notify.Data{
+			"report_name":   name,
+			"business_type": businessType,
+			"start_date":    start,
+			"end_date":      end,
+			"message":       message,
+		},
+	}
+}
+
+// reportCommentedMessage 构造报告收到批阅的通知
+func reportCommentedMessage(acct string, r xixunyun.Report) notify.Message {
+	msg := reportSubmittedMessage(acct, r.BusinessType, r.StartDate, r.EndDate, "")
+	msg.Event = notify.EventReportCommented
+	msg.Data["comment"], msg.Data["commenter"] = r.Comment, r.Commenter
+	return msg
+}
+
+var (
+	notifyAccount    string
+	notifyScheduleID int
+)
+
+var NotifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "管理通知渠道",
+	Long: `管理签到、报告与定时任务的通知渠道。一个账号可以同时配置多个渠道，
+未指定账号的渠道对所有账号生效。命令行 -k 指定的 Server酱密钥与这里的渠道同时生效。`,
+}
+
+var notifyAddCmd = &cobra.Command{
+	Use:   "add <URL | 类型 [key=value...]>",
+	Short: "添加通知渠道",
+	Long: `添加通知渠道，可以使用一个通知 URL，也可以使用渠道类型加 key=value 形式的参数。
+支持的 URL：serverchan://SENDKEY、bark(s)://host/device_key、ntfy(s)://host/topic、
+gotify(s)://host/token、smtp(s)://user:pass@host:port/?to=a@example.com、webhook(s)://host/path、
+dingtalk://ACCESS_TOKEN?secret=SEC...、wecom://KEY、feishu://TOKEN?secret=...。`,
+	Example: `  xixun notify add -a 账号 serverchan://SCT...
+  xixun notify add --schedule 3 barks://api.day.app/<device_key>
+  xixun notify add serverchan sendkey=SCT... channel=9`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var c utils.NotifyChannel
+		var err error
+		if notify.IsURL(args[0]) && len(args) == 1 {
+			c, err = parseNotifyURL(args[0])
+		} else {
+			c, err = parseNotifyArgs(args[0], args[1:])
+		}
+		if err != nil {
+			fmt.Println("添加通知渠道失败:", err)
+			return
+		}
+		c.Account, c.ScheduleID = notifyAccount, notifyScheduleID
+		if notifyScheduleID != 0 {
+			task, err := utils.GetSchedule(notifyScheduleID)
+			if err != nil {
+				fmt.Println("添加通知渠道失败:", err)
+				return
+			}
+			c.Account = task.Account
+		}
+		id, err := utils.AddNotifyChannel(c)
+		if err != nil {
+			fmt.Println("添加通知渠道失败:", err)
+			return
+		}
+		fmt.Printf("通知渠道已添加，ID：%d\n", id)
+	},
+}
+
+// parseNotifyURL 将通知 URL 解析为通知渠道，并检查参数是否完整
+func parseNotifyURL(raw string) (utils.NotifyChannel, error) {
+	kind, cfg, err := notify.ParseURL(raw)
+	if err != nil {
+		return utils.NotifyChannel{}, err
+	}
+	c := utils.NotifyChannel{Kind: kind, Config: cfg, Enabled: true}
+	_, err = newNotifier(c)
+	return c, err
+}
+
+// parseNotifyArgs 将渠道类型与 key=value 形式的参数解析为通知渠道，并检查参数是否完整
+func parseNotifyArgs(kind string, args []string) (utils.NotifyChannel, error) {
+	cfg := notify.Config{}
+	for _, arg := range args {
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok || k == "" {
+			return utils.NotifyChannel{}, fmt.Errorf("参数格式错误: %s(应为 key=value)", arg)
+		}
+		cfg[k] = v
+	}
+	c := utils.NotifyChannel{Kind: kind, Config: cfg, Enabled: true}
+	_, err := newNotifier(c)
+	return c, err
+}
+
+var notifyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出通知渠道",
+	Run: func(cmd *cobra.Command, args []string) {
+		channels, err := utils.ListNotifyChannels(notifyAccount)
+		if err != nil {
+			fmt.Println("查询通知渠道失败:", err)
+			return
+		}
+		if len(channels) == 0 {
+			fmt.Println("没有通知渠道。")
+			return
+		}
+		for _, c := range channels {
+			acct := c.Account
+			if acct == "" {
+				acct = "(所有账号)"
+			}
+			if c.ScheduleID != 0 {
+				acct += fmt.Sprintf(" 定时任务[%d]", c.ScheduleID)
+			}
+			state := "启用"
+			if !c.Enabled {
+				state = "停用"
+			}
+			fmt.Printf("ID: %d, 账号: %s, 类型: %s, 状态: %s, 参数: %s\n", c.ID, acct, c.Kind, state, maskConfig(c.Config))
+		}
+	},
+}
+
+var notifyRmCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "删除通知渠道",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			fmt.Println("无效的 ID:", args[0])
+			return
+		}
+		if err := utils.DeleteNotifyChannel(id); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println("通知渠道已删除。")
+	},
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "向账号的所有通知渠道发送一条测试消息",
+	Run: func(cmd *cobra.Command, args []string) {
+		d := newDispatcher(notifyAccount, notifyScheduleID, secret_key)
+		if d.Len() == 0 {
+			fmt.Println("没有可用的通知渠道。")
+			return
+		}
+		err := d.Send(context.Background(), renderMessage(notify.Message{Event: notify.EventTest, Account: notifyAccount}))
+		if err != nil {
+			fmt.Println("部分通知渠道发送失败:\n" + err.Error())
+			return
+		}
+		fmt.Printf("测试通知已发送到 %d 个渠道。\n", d.Len())
+	},
+}
+
+// maskConfig 隐藏渠道参数中的密钥，较长的密钥只显示前 4 个字符；webhook 地址只显示主机
+func maskConfig(cfg map[string]string) string {
+	keys := make([]string, 0, len(cfg))
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := cfg[k]
+		if v == "" {
+			continue
+		}
+		switch k {
+		case "channel", "base_url", "server", "host", "port", "from", "to", "username", "tls", "topic",
+			"group", "sound", "level", "icon", "priority", "tags":
+		case "url":
+			if u, err := url.Parse(v); err == nil && u.Host != "" {
+				v = u.Scheme + "://" + u.Host + "/****"
+			}
+		case "password":
+			v = "****"
+		default:
+			if len(v) > 8 {
+				v = v[:4] + "****"
+			} else {
+				v = "****"
+			}
+		}
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+func init() {
+	NotifyCmd.PersistentFlags().StringVarP(&notifyAccount, "account", "a", "", "账号(为空时对所有账号生效)")
+	notifyAddCmd.Flags().IntVar(&notifyScheduleID, "schedule", 0, "只用于该定时任务(账号使用任务的账号)")
+	notifyTestCmd.Flags().IntVar(&notifyScheduleID, "schedule", 0, "同时发送到该定时任务的渠道")
+	notifyTestCmd.Flags().StringVarP(&secret_key, "secret_key", "k", "", "server酱密钥(同时发送到该渠道)")
+	NotifyCmd.AddCommand(notifyAddCmd, notifyListCmd, notifyRmCmd, notifyTestCmd)
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/notify"
+	"xixunyunsign/utils"
+)
+
+var (
+	noRetry     bool
+	retryMax    int
+	retryUntil  string
+	retryStatus string
+	retryLimit  int
+)
+
+// RetryCmd 管理签到失败后的重试队列
+var RetryCmd = &cobra.Command{
+	Use:   "retry",
+	Short: "管理签到失败后的重试队列",
+}
+
+var retryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "查看重试队列",
+	Run: func(cmd *cobra.Command, args []string) {
+		listRetries()
+	},
+}
+
+var retryRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "执行已到重试时间的任务(守护进程会自动执行，适合配合系统 cron 或 GitHub Actions 使用)",
+	Run: func(cmd *cobra.Command, args []string) {
+		processRetries(context.Background(), time.Now())
+	},
+}
+
+func init() {
+	retryListCmd.Flags().StringVarP(&retryStatus, "status", "s", "", "状态(pending/succeeded/exhausted/expired/failed)")
+	retryListCmd.Flags().IntVarP(&retryLimit, "limit", "n", 50, "最多显示条数(0 为不限制)")
+	retryRunCmd.Flags().StringVarP(&secret_key, "secret_key", "k", "", "server酱密钥(用于重试结果通知)")
+	retryRunCmd.Flags().BoolVar(&noRelogin, "no_relogin", false, "token 失效时不使用已保存的密码自动重新登录")
+
+	RetryCmd.AddCommand(retryListCmd)
+	RetryCmd.AddCommand(retryRunCmd)
+}
+
+// addRetryFlags 为会发起签到的命令注册重试相关参数
+func addRetryFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&noRetry, "no_retry", false, "网络错误或服务端错误时不加入重试队列")
+	cmd.Flags().IntVar(&retryMax, "retry_max", 5, "最多尝试次数(包括第一次)")
+	cmd.Flags().StringVar(&retryUntil, "retry_until", "23:00", "重试截止时间(当天的 HH:MM，超过后不再重试)")
+}
+
+// retryDeadline 返回 now 所在当天的重试截止时间
+func retryDeadline(now time.Time) (time.Time, error) {
+	loc, err := location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	clock, err := time.Parse("15:04", retryUntil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("重试截止时间 %q 格式不正确，应为 HH:MM", retryUntil)
+	}
+	today := now.In(loc)
+	return time.Date(today.Year(), today.Month(), today.Day(), clock.Hour(), clock.Minute(), 0, 0, loc), nil
+}
+
+// enqueueSignRetry 在签到遇到临时性错误时将其加入重试队列，返回重试队列是否已接管这次失败的通知。
+// 只允许尝试一次或第一次重试就晚于截止时间时不再重试，直接发送放弃重试的通知，返回的任务状态为 exhausted 或 expired。
+func enqueueSignRetry(p signParams, scheduleID int, cause error) (utils.RetryItem, bool) {
+	if noRetry || !utils.IsRetryable(cause) {
+		return utils.RetryItem{}, false
+	}
+	now := time.Now()
+	deadline, err := retryDeadline(now)
+	if err != nil {
+		log.Println(err)
+		return utils.RetryItem{}, false
+	}
+	payload, err := utils.EncodePayload(p.payload())
+	if err != nil {
+		log.Println(err)
+		return utils.RetryItem{}, false
+	}
+	item, err := utils.EnqueueRetry(utils.RetryItem{
+		Kind:        utils.JobTypeSign,
+		Account:     p.Account,
+		ScheduleID:  scheduleID,
+		Payload:     payload,
+		MaxAttempts: retryMax,
+		Deadline:    deadline,
+		LastError:   cause.Error(),
+		ErrorClass:  utils.ErrorClass(cause),
+	}, now)
+	if err != nil {
+		log.Printf("未加入重试队列: %v\n", err)
+		return utils.RetryItem{}, false
+	}
+	if item.Status != utils.RetryStatusPending {
+		notifyRetryGaveUp(item)
+	}
+	return item, true
+}
+
+// processRetries 执行所有到达重试时间的任务，并通知已放弃重试的任务
+func processRetries(ctx context.Context, now time.Time) {
+	expired, err := utils.ExpireRetries(now)
+	if err != nil {
+		log.Printf("检查重试队列失败: %v\n", err)
+		return
+	}
+	for _, item := range expired {
+		notifyRetryGaveUp(item)
+	}
+
+	due, err := utils.DueRetries(now)
+	if err != nil {
+		log.Printf("读取重试队列失败: %v\n", err)
+		return
+	}
+	for _, item := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		retryItem(ctx, item)
+	}
+}
+
+// retryItem 使用任务类型对应的处理函数重新执行一个重试任务，并更新其状态
+func retryItem(ctx context.Context, item utils.RetryItem) {
+	log.Printf("第 %d 次尝试执行(重试任务[%d])，账号：%s\n", item.Attempts+1, item.ID, item.Account)
+	start := time.Now()
+	err := jobHandlers().Run(ctx, utils.Job{ScheduleID: item.ScheduleID, Type: item.Kind, Account: item.Account,
+		Payload: item.Payload, Trigger: utils.JobTriggerRetry})
+	if item.ScheduleID != 0 {
+		record := utils.JobRun{ScheduleID: item.ScheduleID, Account: item.Account, Trigger: utils.JobTriggerRetry,
+			StartedAt: start, Duration: time.Since(start), Outcome: utils.JobOutcomeSuccess, RetryCount: item.Attempts}
+		if err != nil {
+			record.Outcome, record.ErrorClass, record.Error = utils.JobOutcomeFailed, utils.ErrorClass(err), err.Error()
+		}
+		if _, recErr := utils.RecordJobRun(record); recErr != nil {
+			log.Printf("记录任务执行历史失败: %v\n", recErr)
+		}
+	}
+
+	now := time.Now()
+	if err == nil {
+		if err := utils.MarkRetrySucceeded(item.ID, now); err != nil {
+			log.Println(err)
+		}
+		log.Printf("重试任务[%d]签到成功\n", item.ID)
+		sendNotify(ctx, secret_key, notify.Message{
+			Event:      notify.EventSignSuccess,
+			Account:    item.Account,
+			ScheduleID: item.ScheduleID,
+			Data:       notify.Data{"retry": true, "attempts": item.Attempts + 1, "address": retryAddress(item)},
+		})
+		return
+	}
+
+	item, markErr := utils.MarkRetryFailed(item, err, now)
+	if markErr != nil {
+		log.Println(markErr)
+		return
+	}
+	if item.Status == utils.RetryStatusPending {
+		log.Printf("重试任务[%d]签到失败，将于 %s 再次重试: %v\n", item.ID, item.NextAttemptAt.Local().Format("15:04:05"), err)
+		return
+	}
+	notifyRetryGaveUp(item)
+}
+
+// retryGaveUpReason 返回任务终止状态对应的放弃重试原因
+func retryGaveUpReason(status string) string {
+	return map[string]string{
+		utils.RetryStatusExhausted: "已达到最大尝试次数",
+		utils.RetryStatusExpired:   "已超过重试截止时间",
+		utils.RetryStatusFailed:    "遇到无法重试的错误",
+	}[status]
+}
+
+// notifyRetryGaveUp 在任务不再重试时发送最终通知
+func notifyRetryGaveUp(item utils.RetryItem) {
+	reason := retryGaveUpReason(item.Status)
+	log.Printf("重试任务[%d]放弃重试(%s)，账号：%s，最后错误: %s\n", item.ID, reason, item.Account, item.LastError)
+	sendNotify(context.Background(), secret_key, notify.Message{
+		Event:      notify.EventSignFailure,
+		Account:    item.Account,
+		ScheduleID: item.ScheduleID,
+		Data: notify.Data{"retry": true, "attempts": item.Attempts, "reason": reason, "message": item.LastError,
+			"address": retryAddress(item)},
+	})
+}
+
+// retryAddress 返回签到重试任务的签到地址，其他任务返回空
+func retryAddress(item utils.RetryItem) string {
+	var p utils.SignPayload
+	if item.Kind != utils.JobTypeSign || (utils.Job{Payload: item.Payload}).Decode(&p) != nil {
+		return ""
+	}
+	return p.Address
+}
+
+// retryWorker 在守护进程中后台处理重试队列，同一时间只有一轮在执行。
+type retryWorker struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	running bool
+	wg      sync.WaitGroup
+}
+
+// trigger 开始一轮重试，上一轮尚未结束时直接返回
+func (w *retryWorker) trigger() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running {
+		return
+	}
+	w.running = true
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		processRetries(w.ctx, time.Now())
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+}
+
+// wait 等待正在执行的一轮重试结束
+func (w *retryWorker) wait() {
+	w.wg.Wait()
+}
+
+func listRetries() {
+	items, err := utils.ListRetries(retryStatus, retryLimit)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if len(items) == 0 {
+		fmt.Println("重试队列为空")
+		return
+	}
+	for _, item := range items {
+		next := "-"
+		if item.Status == utils.RetryStatusPending {
+			next = item.NextAttemptAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("[%d] %-10s %-12s 尝试:%d/%d 下次:%s 截止:%s\n", item.ID, item.Status, item.Account,
+			item.Attempts, item.MaxAttempts, next, item.Deadline.Local().Format("2006-01-02 15:04"))
+		if item.LastError != "" {
+			fmt.Printf("    %s (%s)\n", item.LastError, item.ErrorClass)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/utils"
+	"xixunyunsign/xixunyun"
+)
+
+// run 命令的退出码，CI 可以据此判断失败的步骤以及是否值得重试
+const (
+	ExitOK       = 0  // 成功(今日已签到也算成功)
+	ExitUsage    = 1  // 参数缺失或错误
+	ExitLogin    = 2  // 登录失败
+	ExitQuery    = 3  // 更新签到坐标失败
+	ExitSign     = 4  // 签到失败
+	ExitTempFail = 75 // 网络错误、超时、服务器维护等临时性错误，稍后重试可能成功
+)
+
+// ExitError 是带有进程退出码的错误
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// ExitCode 返回命令执行结果对应的进程退出码
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitUsage
+}
+
+var (
+	runLogin         bool
+	runRefreshCoords bool
+	runSign          bool
+)
+
+var RunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "在一个进程中依次执行登录、更新签到坐标、签到，适合 CI 等无状态环境",
+	Long: `在一个进程中依次执行登录、更新签到坐标、签到，任一步骤失败时以非零退出码退出。
+未指定 --login、--refresh_coords、--sign 时执行全部步骤。
+
+参数未在命令行中指定时从环境变量读取(XIXUN_ACCOUNT、XIXUN_PASSWORD、XIXUN_SCHOOL_ID、
+XIXUN_ADDRESS、XIXUN_ADDRESS_NAME、XIXUN_LATITUDE、XIXUN_LONGITUDE、XIXUN_PROVINCE、
+XIXUN_CITY、XIXUN_REMARK、XIXUN_COMMENT、XIXUN_SECRET_KEY)，通知 URL 从 XIXUN_NOTIFY_URLS 读取。
+配合 XIXUN_STATELESS=1 使用内存数据库，不会创建 config.db。
+
+退出码: 0 成功(含今日已签到)，1 参数错误，2 登录失败，3 更新坐标失败，4 签到失败，
+75 临时性错误(网络、超时、服务器维护等，稍后重试可能成功)。`,
+	Example: `  XIXUN_STATELESS=1 XIXUN_ACCOUNT=账号 XIXUN_PASSWORD=密码 XIXUN_ADDRESS=地址 xixun run
+  xixun run --login --refresh-coords --sign -a 账号 -p 密码 --address "xx省xx市xx区xx路"`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChain(cmd)
+	},
+}
+
+func init() {
+	RunCmd.Flags().BoolVar(&runLogin, "login", false, "使用账号密码登录")
+	RunCmd.Flags().BoolVar(&runRefreshCoords, "refresh_coords", false, "查询签到信息并更新应签到坐标")
+	RunCmd.Flags().BoolVar(&runRefreshCoords, "refresh-coords", false, "同 --refresh_coords")
+	RunCmd.Flags().MarkHidden("refresh-coords")
+	RunCmd.Flags().BoolVar(&runSign, "sign", false, "签到")
+	RunCmd.Flags().StringVarP(&account, "account", "a", "", "账号(XIXUN_ACCOUNT)")
+	RunCmd.Flags().StringVarP(&password, "password", "p", "", "密码(XIXUN_PASSWORD)")
+	RunCmd.Flags().StringVarP(&school_id, "school_id", "i", "7", "学校id(XIXUN_SCHOOL_ID)")
+	RunCmd.Flags().StringVar(&address, "address", "", "地址(XIXUN_ADDRESS)")
+	RunCmd.Flags().StringVar(&address_name, "address_name", "", "地址名称(XIXUN_ADDRESS_NAME)")
+	RunCmd.Flags().StringVar(&latitude, "latitude", "", "纬度(XIXUN_LATITUDE，为空时使用更新后的坐标)")
+	RunCmd.Flags().StringVar(&longitude, "longitude", "", "经度(XIXUN_LONGITUDE，为空时使用更新后的坐标)")
+	RunCmd.Flags().StringVar(&province, "province", "", "省份(XIXUN_PROVINCE)")
+	RunCmd.Flags().StringVar(&city, "city", "", "城市(XIXUN_CITY)")
+	RunCmd.Flags().StringVar(&remark, "remark", "0", "备注(XIXUN_REMARK)")
+	RunCmd.Flags().StringVar(&comment, "comment", "", "评论(XIXUN_COMMENT)")
+	RunCmd.Flags().StringVarP(&secret_key, "secret_key", "k", "", "server酱密钥(XIXUN_SECRET_KEY)")
+}
+
+// flagOrEnv 返回参数值，命令行未指定该参数且环境变量不为空时使用环境变量
+func flagOrEnv(cmd *cobra.Command, flag, value, env string) string {
+	if !cmd.Flags().Changed(flag) {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return value
+}
+
+// stepFailed 打印失败的步骤，临时性错误使用 ExitTempFail 以便 CI 重试
+func stepFailed(step string, code int, err error) error {
+	fmt.Printf("%s失败: %s\n", step, describeError(err))
+	if utils.IsRetryable(err) {
+		code = ExitTempFail
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+func runChain(cmd *cobra.Command) error {
+	doLogin, doRefresh, doSign := runLogin, runRefreshCoords, runSign
+	if !doLogin && !doRefresh && !doSign {
+		doLogin, doRefresh, doSign = true, true, true
+	}
+	acct := flagOrEnv(cmd, "account", account, "XIXUN_ACCOUNT")
+	pass := flagOrEnv(cmd, "password", password, "XIXUN_PASSWORD")
+	p := signParams{
+		Account:     acct,
+		Address:     flagOrEnv(cmd, "address", address, "XIXUN_ADDRESS"),
+		AddressName: flagOrEnv(cmd, "address_name", address_name, "XIXUN_ADDRESS_NAME"),
+		Latitude:    flagOrEnv(cmd, "latitude", latitude, "XIXUN_LATITUDE"),
+		Longitude:   flagOrEnv(cmd, "longitude", longitude, "XIXUN_LONGITUDE"),
+		Province:    flagOrEnv(cmd, "province", province, "XIXUN_PROVINCE"),
+		City:        flagOrEnv(cmd, "city", city, "XIXUN_CITY"),
+		Remark:      flagOrEnv(cmd, "remark", remark, "XIXUN_REMARK"),
+		Comment:     flagOrEnv(cmd, "comment", comment, "XIXUN_COMMENT"),
+	}
+	key := flagOrEnv(cmd, "secret_key", secret_key, "XIXUN_SECRET_KEY")
+
+	switch {
+	case acct == "":
+		err := errors.New("未提供账号，请使用 -a 或环境变量 XIXUN_ACCOUNT")
+		fmt.Println(err)
+		return &ExitError{Code: ExitUsage, Err: err}
+	case doLogin && pass == "":
+		err := errors.New("登录需要密码，请使用 -p 或环境变量 XIXUN_PASSWORD")
+		fmt.Println(err)
+		return &ExitError{Code: ExitUsage, Err: err}
+	case doSign && p.Address == "":
+		err := errors.New("签到需要地址，请使用 --address 或环境变量 XIXUN_ADDRESS")
+		fmt.Println(err)
+		return &ExitError{Code: ExitUsage, Err: err}
+	}
+
+	ctx := context.Background()
+	if doLogin {
+		if err := loginAndSave(ctx, acct, pass, flagOrEnv(cmd, "school_id", school_id, "XIXUN_SCHOOL_ID")); err != nil {
+			return stepFailed("登录", ExitLogin, err)
+		}
+		fmt.Println("登录成功！")
+	}
+	if doRefresh {
+		if _, _, err := refreshCoordinates(ctx, acct); err != nil {
+			return stepFailed("更新签到坐标", ExitQuery, err)
+		}
+		fmt.Println("应签到位置的经纬度已更新。")
+	}
+	if !doSign {
+		return nil
+	}
+
+	used, resp, err := performSign(ctx, p, utils.SignTriggerManual)
+	if errors.Is(err, xixunyun.ErrAlreadySigned) {
+		fmt.Println("今日已签到，无需重复签到。")
+		return nil
+	}
+	if err != nil {
+		sendNotify(ctx, key, signFailureMessage(used, err, ""))
+		return stepFailed("签到", ExitSign, err)
+	}
+	fmt.Println("签到成功！")
+	sendNotify(ctx, key, signSuccessMessage(used, resp))
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xixunyunsign/cmd"
+	"xixunyunsign/notify"
+	"xixunyunsign/utils"
+)
+
+func TestRunStateless(t *testing.T) {
+	utils.CloseDB()
+	utils.Stateless = true
+	utils.DBPath = filepath.Join(t.TempDir(), "config.db")
+	t.Cleanup(func() {
+		utils.CloseDB()
+		utils.Stateless = false
+	})
+
+	var paths []string
+	down := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if down {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		switch r.URL.Path {
+		case "/login/api":
+			if r.FormValue("password") != "pass" {
+				w.Write([]byte(`{"code":40000,"message":"账号或密码错误"}`))
+				return
+			}
+			w.Write([]byte(`{"code":20000,"message":"ok","data":{"token":"tok","user_number":"user","school_id":"7"}}`))
+		case "/signin40/homepage":
+			w.Write([]byte(`{"code":20000,"message":"ok","data":{"sign_resources_info":{"mid_sign_latitude":"32.05","mid_sign_longitude":118.79}}}`))
+		case "/signin_rsa":
+			w.Write([]byte(`{"code":20000,"message":"签到成功"}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+	cmd.APIBaseURL = ts.URL
+
+	// 通知渠道可以通过环境变量配置
+	var events []string
+	var bodies []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload notify.WebhookPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		events = append(events, payload.Event)
+		bodies = append(bodies, payload.Body)
+	}))
+	defer hook.Close()
+	t.Setenv(cmd.EnvNotifyURLs, "webhook://"+strings.TrimPrefix(hook.URL, "http://")+"/hook")
+
+	t.Setenv("XIXUN_ACCOUNT", "user")
+	t.Setenv("XIXUN_PASSWORD", "pass")
+	t.Setenv("XIXUN_ADDRESS", "江苏省南京市玄武区北京东路41号")
+	require.NoError(t, utils.InitDB())
+
+	cmd.RunCmd.SetArgs([]string{"--login", "--refresh-coords", "--sign"})
+	require.NoError(t, cmd.RunCmd.Execute())
+	assert.Equal(t, []string{"/login/api", "/signin40/homepage", "/signin_rsa"}, paths)
+	assert.Equal(t, []string{notify.EventSignSuccess}, events)
+	require.Len(t, bodies, 1)
+	assert.Contains(t, bodies[0], "签到地址：江苏省南京市玄武区北京东路41号", "通知使用内置模板生成")
+
+	logs, err := utils.QuerySignLogs(utils.SignLogFilter{Account: "user"})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "32.05", logs[0].Latitude, "使用更新后的坐标签到")
+	_, err = os.Stat(utils.DBPath)
+	assert.True(t, os.IsNotExist(err), "无状态模式不应创建数据库文件")
+
+	// 登录失败时以对应的退出码退出，不再执行后续步骤
+	paths = nil
+	cmd.RunCmd.SetArgs([]string{"--login", "--sign", "-p", "wrong"})
+	err = cmd.RunCmd.Execute()
+	assert.Equal(t, cmd.ExitLogin, cmd.ExitCode(err))
+	assert.Equal(t, []string{"/login/api"}, paths)
+
+	// 服务端错误属于临时性错误
+	down = true
+	cmd.RunCmd.SetArgs([]string{"--login", "--sign", "-p", "pass"})
+	assert.Equal(t, cmd.ExitTempFail, cmd.ExitCode(cmd.RunCmd.Execute()))
+}
+
+func TestStatelessFlag(t *testing.T) {
+	utils.CloseDB()
+	utils.DBPath = filepath.Join(t.TempDir(), "config.db")
+	t.Cleanup(func() {
+		utils.CloseDB()
+		utils.Stateless = false
+	})
+
+	// 数据库在解析参数后才打开，--stateless 因此生效
+	root := &cobra.Command{Use: "xixun"}
+	cmd.AddDatabaseFlags(root)
+	root.AddCommand(&cobra.Command{Use: "noop", Run: func(*cobra.Command, []string) {}})
+	root.SetArgs([]string{"--stateless", "noop"})
+	require.NoError(t, root.Execute())
+	assert.True(t, utils.Stateless)
+	_, err := utils.SchemaVersion()
+	require.NoError(t, err)
+	assert.NoFileExists(t, utils.DBPath)
+}
@@ -0,0 +1,531 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/utils"
+)
+
+var (
+	cronExpr            string
+	runAt               string
+	scheduleAddPreview  int
+	scheduleListPreview int
+	scheduleDisabled    bool
+	missedPolicy        string
+	dayPolicy           string
+	scheduleTimezone    string
+	jobType             string
+	scheduleNotifyURLs  []string
+
+	reportBusinessType string
+	reportContent      string
+	reportContentFile  string
+	reportAttachment   string
+
+	runsScheduleID int
+	runsFrom       string
+	runsTo         string
+	runsOutcome    string
+	runsLimit      int
+	runsMissing    bool
+)
+
+// ScheduleCmd 管理定时任务
+var ScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "管理定时任务(签到、更新坐标、刷新 token、提交报告等)",
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "添加定时任务",
+	Example: `  xixun schedule add -a 账号 --cron "30 8 * * *" --address "xx省xx市xx区xx路"
+  xixun schedule add -a 账号 --cron "@every 12h" --latitude 30.1 --longitude 120.2 --preview 3
+  xixun schedule add -a 账号 --type query --cron "0 7 * * 1"
+  xixun schedule add -a 账号 --type report --business_type week --content_file week.json --cron "0 20 * * 5"
+  xixun schedule add --type school_refresh --cron "@monthly"
+  xixun schedule add -a 账号 --type report --business_type month --content_file month.json --at 2026-10-31T20:00:00+08:00`,
+	Run: func(cmd *cobra.Command, args []string) {
+		addSchedule()
+	},
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "查看所有定时任务",
+	Run: func(cmd *cobra.Command, args []string) {
+		listSchedules()
+	},
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:     "rm <id>",
+	Aliases: []string{"remove"},
+	Short:   "删除定时任务",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		updateSchedule(args[0], "删除", utils.DeleteSchedule)
+	},
+}
+
+var scheduleEnableCmd = &cobra.Command{
+	Use:   "enable <id>",
+	Short: "启用定时任务",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		updateSchedule(args[0], "启用", func(id int) error { return utils.SetScheduleEnabled(id, true) })
+	},
+}
+
+var scheduleDisableCmd = &cobra.Command{
+	Use:   "disable <id>",
+	Short: "停用定时任务",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		updateSchedule(args[0], "停用", func(id int) error { return utils.SetScheduleEnabled(id, false) })
+	},
+}
+
+var schedulePolicyCmd = &cobra.Command{
+	Use:   "policy <id> <skip|run-once|notify-only>",
+	Short: "设置定时任务错过执行时的处理策略",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		updateSchedule(args[0], "设置为 "+args[1], func(id int) error { return utils.SetScheduleMissedPolicy(id, args[1]) })
+	},
+}
+
+var scheduleDaysCmd = &cobra.Command{
+	Use:   "days <id> <everyday|workdays|calendar:name>",
+	Short: "设置定时任务在哪些日期签到",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		updateSchedule(args[0], "设置为 "+args[1], func(id int) error { return utils.SetScheduleDayPolicy(id, args[1]) })
+	},
+}
+
+var scheduleTimezoneCmd = &cobra.Command{
+	Use:   "timezone <id> <时区|default>",
+	Short: "设置定时任务的时区(default 为使用全局 --tz)",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		tz := args[1]
+		if tz == "default" {
+			tz = ""
+		}
+		updateSchedule(args[0], "设置为 "+args[1], func(id int) error { return utils.SetScheduleTimezone(id, tz) })
+	},
+}
+
+var scheduleRunsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "查看定时任务的执行记录(计划时间、实际开始时间、耗时、结果)",
+	Run: func(cmd *cobra.Command, args []string) {
+		showJobRuns()
+	},
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run <id>",
+	Short: "立即执行一次定时任务(不影响调度)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runScheduleNow(args[0])
+	},
+}
+
+func init() {
+	scheduleAddCmd.Flags().StringVarP(&account, "account", "a", "", "账号(school_refresh 任务不需要)")
+	scheduleAddCmd.Flags().StringVar(&cronExpr, "cron", "", "cron 表达式(分 时 日 月 周，或 @daily、@every 1h 等)")
+	scheduleAddCmd.Flags().StringVar(&runAt, "at", "", "只在该时间执行一次(RFC3339 格式，如 2026-10-31T20:00:00+08:00)，与 --cron 二选一")
+	scheduleAddCmd.Flags().StringVar(&jobType, "type", utils.JobTypeSign, "任务类型("+strings.Join(utils.JobTypes, "/")+")")
+	scheduleAddCmd.Flags().StringVar(&address, "address", "", "[sign] 地址(为空时签到时需提供经纬度)")
+	scheduleAddCmd.Flags().StringVar(&address_name, "address_name", "", "[sign] 地址名称")
+	scheduleAddCmd.Flags().StringVar(&latitude, "latitude", "", "[sign] 纬度(为空时使用数据库中保存的值)")
+	scheduleAddCmd.Flags().StringVar(&longitude, "longitude", "", "[sign] 经度(为空时使用数据库中保存的值)")
+	scheduleAddCmd.Flags().StringVarP(&province, "province", "p", "", "[sign] 省份(为空时从地址中提取)")
+	scheduleAddCmd.Flags().StringVarP(&city, "city", "c", "", "[sign] 城市(为空时从地址中提取)")
+	scheduleAddCmd.Flags().StringVar(&remark, "remark", "0", "[sign] 备注")
+	scheduleAddCmd.Flags().StringVar(&comment, "comment", "", "[sign] 评论")
+	scheduleAddCmd.Flags().StringVar(&reportBusinessType, "business_type", "week", "[report] 报告类型(day/week/month)，起止日期按执行时间所在的日、周、月计算")
+	scheduleAddCmd.Flags().StringVar(&reportContent, "content", "", "[report] 报告内容")
+	scheduleAddCmd.Flags().StringVar(&reportContentFile, "content_file", "", "[report] 报告内容文件(每次执行时读取)")
+	scheduleAddCmd.Flags().StringVar(&reportAttachment, "attachment", "", "[report] 附件地址")
+	scheduleAddCmd.Flags().BoolVar(&scheduleDisabled, "disabled", false, "添加后暂不启用")
+	scheduleAddCmd.Flags().StringVar(&scheduleTimezone, "timezone", "", "该任务使用的时区(如 Asia/Shanghai，为空时使用全局 --tz)")
+	scheduleAddCmd.Flags().StringVar(&dayPolicy, "day_policy", utils.DayPolicyEveryday, "签到日期(everyday 每天 / workdays 法定工作日 / calendar:<日历名> 按导入的日历)")
+	scheduleAddCmd.Flags().StringVar(&missedPolicy, "missed_policy", utils.MissedPolicySkip, "守护进程停机期间错过执行时的处理策略(skip/run-once/notify-only)")
+	scheduleAddCmd.Flags().IntVar(&scheduleAddPreview, "preview", 5, "显示接下来几次的执行时间")
+	scheduleAddCmd.Flags().StringArrayVar(&scheduleNotifyURLs, "notify", nil, "只用于该任务的通知 URL(可多次指定，如 serverchan://SENDKEY)")
+	scheduleAddCmd.MarkFlagsOneRequired("cron", "at")
+	scheduleAddCmd.MarkFlagsMutuallyExclusive("cron", "at")
+
+	scheduleListCmd.Flags().StringVarP(&account, "account", "a", "", "账号(为空时显示全部账号)")
+	scheduleListCmd.Flags().IntVar(&scheduleListPreview, "preview", 1, "每个任务显示接下来几次的执行时间")
+
+	scheduleRunCmd.Flags().BoolVar(&noRelogin, "no_relogin", false, "token 失效时不使用已保存的密码自动重新登录")
+
+	scheduleRunsCmd.Flags().IntVarP(&runsScheduleID, "id", "i", 0, "任务 ID(为空时查询全部任务)")
+	scheduleRunsCmd.Flags().StringVarP(&runsFrom, "from", "f", "", "开始日期(格式为2006-01-02，包含当天，--missing 时默认为 7 天前)")
+	scheduleRunsCmd.Flags().StringVarP(&runsTo, "to", "t", "", "结束日期(格式为2006-01-02，包含当天)")
+	scheduleRunsCmd.Flags().StringVarP(&runsOutcome, "outcome", "o", "", "执行结果(success/failed/skipped)")
+	scheduleRunsCmd.Flags().IntVarP(&runsLimit, "limit", "n", 50, "最多显示条数(0 为不限制)")
+	scheduleRunsCmd.Flags().BoolVar(&runsMissing, "missing", false, "列出按计划应执行但没有任何执行记录的时间")
+
+	ScheduleCmd.AddCommand(scheduleAddCmd)
+	ScheduleCmd.AddCommand(scheduleListCmd)
+	ScheduleCmd.AddCommand(scheduleRemoveCmd)
+	ScheduleCmd.AddCommand(scheduleEnableCmd)
+	ScheduleCmd.AddCommand(scheduleDisableCmd)
+	ScheduleCmd.AddCommand(schedulePolicyCmd)
+	ScheduleCmd.AddCommand(scheduleDaysCmd)
+	ScheduleCmd.AddCommand(scheduleTimezoneCmd)
+	ScheduleCmd.AddCommand(scheduleRunCmd)
+	ScheduleCmd.AddCommand(scheduleRunsCmd)
+}
+
+func addSchedule() {
+	payload, err := schedulePayload(jobType)
+	if err != nil {
+		fmt.Println("添加定时任务失败:", err)
+		return
+	}
+	var at time.Time
+	if runAt != "" {
+		if at, err = time.Parse(time.RFC3339, runAt); err != nil {
+			fmt.Println("添加定时任务失败: 执行时间应为 RFC3339 格式，如 2026-10-31T20:00:00+08:00")
+			return
+		}
+	}
+	task := utils.ScheduleTask{
+		Account:      account,
+		JobType:      jobType,
+		RunAt:        at,
+		Payload:      payload,
+		CronExpr:     cronExpr,
+		Enabled:      1,
+		MissedPolicy: missedPolicy,
+		DayPolicy:    dayPolicy,
+		Timezone:     scheduleTimezone,
+	}
+	if scheduleDisabled {
+		task.Enabled = 0
+	}
+	var channels []utils.NotifyChannel
+	for _, raw := range scheduleNotifyURLs {
+		c, err := parseNotifyURL(raw)
+		if err != nil {
+			fmt.Println("添加定时任务失败:", err)
+			return
+		}
+		channels = append(channels, c)
+	}
+
+	id, err := utils.AddSchedule(task)
+	if err != nil {
+		fmt.Println("添加定时任务失败:", err)
+		return
+	}
+	fmt.Printf("定时任务已添加，ID: %d\n", id)
+	for _, c := range channels {
+		c.Account, c.ScheduleID = task.Account, id
+		if _, err := utils.AddNotifyChannel(c); err != nil {
+			fmt.Println("添加通知渠道失败:", err)
+		}
+	}
+	if task.Enabled == 0 {
+		fmt.Printf("任务未启用，使用 `xixun schedule enable %d` 启用。\n", id)
+	}
+	printNextRunTimes(task, scheduleAddPreview, "  ")
+}
+
+// schedulePayload 将命令行参数编码为对应任务类型的参数
+func schedulePayload(jobType string) (string, error) {
+	switch jobType {
+	case utils.JobTypeSign:
+		return utils.EncodePayload(utils.SignPayload{
+			Address:     address,
+			AddressName: address_name,
+			Latitude:    latitude,
+			Longitude:   longitude,
+			Province:    province,
+			City:        city,
+			Remark:      remark,
+			Comment:     comment,
+		})
+	case utils.JobTypeReport:
+		p := utils.ReportPayload{
+			BusinessType: reportBusinessType,
+			Content:      reportContent,
+			ContentFile:  reportContentFile,
+			Attachment:   reportAttachment,
+		}
+		if err := validateReportPayload(p); err != nil {
+			return "", err
+		}
+		// 守护进程的工作目录可能不同，保存绝对路径
+		if p.ContentFile != "" {
+			abs, err := filepath.Abs(p.ContentFile)
+			if err != nil {
+				return "", err
+			}
+			p.ContentFile = abs
+		}
+		return utils.EncodePayload(p)
+	}
+	return "{}", nil
+}
+
+// describeJob 返回定时任务参数的简要说明
+func describeJob(t utils.ScheduleTask) string {
+	job := t.Job("", time.Time{})
+	switch t.JobType {
+	case utils.JobTypeSign:
+		var p utils.SignPayload
+		if err := job.Decode(&p); err != nil {
+			return err.Error()
+		}
+		coords := "数据库坐标"
+		if p.Latitude != "" && p.Longitude != "" {
+			coords = p.Latitude + "," + p.Longitude
+		}
+		return fmt.Sprintf("坐标:%s 地址:%s", coords, p.Address)
+	case utils.JobTypeReport:
+		var p utils.ReportPayload
+		if err := job.Decode(&p); err != nil {
+			return err.Error()
+		}
+		if p.ContentFile != "" {
+			return fmt.Sprintf("报告:%s 内容文件:%s", p.BusinessType, p.ContentFile)
+		}
+		return fmt.Sprintf("报告:%s 内容:%d 字", p.BusinessType, len([]rune(p.Content)))
+	}
+	return ""
+}
+
+func listSchedules() {
+	loc, err := location()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	tasks, err := utils.ListSchedules()
+	if err != nil {
+		fmt.Println("查询定时任务失败:", err)
+		return
+	}
+
+	shown := 0
+	for _, t := range tasks {
+		if account != "" && t.Account != account {
+			continue
+		}
+		shown++
+		state := "启用"
+		switch {
+		case t.Completed():
+			state = "完成"
+		case t.Enabled == 0:
+			state = "停用"
+		}
+		when := fmt.Sprintf("cron:%-16q", t.CronExpr)
+		if t.IsOneShot() {
+			when = "一次性:" + t.RunAt.In(t.Location(loc)).Format("2006-01-02 15:04 MST")
+		}
+		fmt.Printf("[%d] %-4s %-14s %-12s %s %s\n", t.ID, state, t.JobType, t.Account, when, describeJob(t))
+		tz := t.Timezone
+		if tz == "" {
+			tz = Timezone + "(默认)"
+		}
+		lastRun := "从未执行"
+		if !t.LastRunAt.IsZero() {
+			lastRun = t.LastRunAt.In(t.Location(loc)).Format("2006-01-02 15:04:05") + " " + t.LastResult
+		}
+		fmt.Printf("    时区: %s，签到日期: %s，上次执行: %s，错过执行时: %s\n", tz, t.DayPolicy, lastRun, t.MissedPolicy)
+		if t.Enabled != 0 && !t.IsOneShot() {
+			printNextRunTimes(t, scheduleListPreview, "    ")
+		}
+	}
+	if shown == 0 {
+		fmt.Println("没有定时任务，使用 `xixun schedule add` 添加。")
+	}
+}
+
+// printNextRunTimes 打印定时任务接下来 n 次的执行时间（已跳过无需签到的日期）
+func printNextRunTimes(t utils.ScheduleTask, n int, indent string) {
+	if n <= 0 {
+		return
+	}
+	loc, err := location()
+	if err != nil {
+		fmt.Printf("%s%v\n", indent, err)
+		return
+	}
+	times, err := utils.NextRunTimes(t, time.Now().In(loc), n)
+	if err != nil {
+		fmt.Printf("%s%v\n", indent, err)
+		return
+	}
+	for _, next := range times {
+		fmt.Printf("%s下次执行: %s\n", indent, next.Format("2006-01-02 15:04:05 Mon MST"))
+	}
+}
+
+// parseScheduleID 解析命令行中的任务 ID
+func parseScheduleID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("任务 ID %q 无效", arg)
+	}
+	return id, nil
+}
+
+func updateSchedule(arg, action string, fn func(id int) error) {
+	id, err := parseScheduleID(arg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := fn(id); err != nil {
+		fmt.Printf("%s定时任务[%d]失败: %v\n", action, id, err)
+		return
+	}
+	fmt.Printf("定时任务[%d]已%s\n", id, action)
+}
+
+func runScheduleNow(arg string) {
+	id, err := parseScheduleID(arg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	t, err := utils.GetSchedule(id)
+	if err != nil {
+		fmt.Printf("读取定时任务[%d]失败: %v\n", id, err)
+		return
+	}
+
+	start := time.Now()
+	err = jobHandlers().Run(context.Background(), t.Job(utils.JobTriggerManual, time.Time{}))
+	record := utils.JobRun{ScheduleID: id, Account: t.Account, Trigger: utils.JobTriggerManual, StartedAt: start,
+		Duration: time.Since(start), Outcome: utils.JobOutcomeSuccess}
+	if err != nil {
+		record.Outcome, record.ErrorClass, record.Error = utils.JobOutcomeFailed, utils.ErrorClass(err), err.Error()
+	}
+	if _, err := utils.RecordJobRun(record); err != nil {
+		fmt.Println("记录任务执行历史失败:", err)
+	}
+	if err != nil {
+		fmt.Printf("定时任务[%d]执行失败: %s\n", id, describeError(err))
+		return
+	}
+	fmt.Printf("定时任务[%d](%s)执行成功\n", id, t.JobType)
+}
+
+// parseDateRange 解析 --from/--to 日期参数，返回左闭右开的时间范围
+func parseDateRange(from, to string, loc *time.Location) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+	if from != "" {
+		if start, err = time.ParseInLocation("2006-01-02", from, loc); err != nil {
+			return start, end, fmt.Errorf("开始日期格式不正确: %v", err)
+		}
+	}
+	if to != "" {
+		if end, err = time.ParseInLocation("2006-01-02", to, loc); err != nil {
+			return start, end, fmt.Errorf("结束日期格式不正确: %v", err)
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+func showJobRuns() {
+	loc, err := location()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	from, to, err := parseDateRange(runsFrom, runsTo, loc)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if runsMissing {
+		showMissingRuns(from, to, loc)
+		return
+	}
+
+	runs, err := utils.QueryJobRuns(utils.JobRunFilter{ScheduleID: runsScheduleID, From: from, To: to, Outcome: runsOutcome, Limit: runsLimit})
+	if err != nil {
+		fmt.Println("查询任务执行记录失败:", err)
+		return
+	}
+	if len(runs) == 0 {
+		fmt.Println("没有符合条件的执行记录")
+		return
+	}
+	for _, r := range runs {
+		fmt.Printf("[%d] 计划:%s 延迟:%-8s 耗时:%-8s %-7s 来源:%-8s 重试:%d %s\n",
+			r.ScheduleID, r.PlannedAt.In(loc).Format("2006-01-02 15:04:05"), r.Drift().Round(time.Millisecond),
+			r.Duration.Round(time.Millisecond), r.Outcome, r.Trigger, r.RetryCount, r.Account)
+		if r.Error != "" {
+			fmt.Printf("    %s", r.Error)
+			if r.ErrorClass != "" {
+				fmt.Printf(" (%s)", r.ErrorClass)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// showMissingRuns 列出已启用的任务在时间范围内没有执行记录的计划时间
+func showMissingRuns(from, to time.Time, loc *time.Location) {
+	now := time.Now().In(loc)
+	if from.IsZero() {
+		from = now.AddDate(0, 0, -7)
+	}
+	// 只检查已经过去的计划时间
+	if to.IsZero() || to.After(now.Add(-time.Minute)) {
+		to = now.Add(-time.Minute)
+	}
+	tasks, err := utils.LoadSchedules()
+	if err != nil {
+		fmt.Println("查询定时任务失败:", err)
+		return
+	}
+
+	found := false
+	for _, t := range tasks {
+		if runsScheduleID != 0 && t.ID != runsScheduleID {
+			continue
+		}
+		missing, err := utils.MissingRuns(t, from, to, time.Minute)
+		if err != nil {
+			fmt.Printf("[%d] 检查失败: %v\n", t.ID, err)
+			continue
+		}
+		if len(missing) > 0 {
+			found = true
+			fmt.Printf("[%d] %s 共有 %d 次计划执行没有记录\n", t.ID, t.Account, len(missing))
+		}
+		for i, m := range missing {
+			if runsLimit > 0 && i >= runsLimit {
+				fmt.Printf("    ...\n")
+				break
+			}
+			fmt.Printf("    %s\n", m.In(t.Location(loc)).Format("2006-01-02 15:04:05 MST"))
+		}
+	}
+	if !found {
+		fmt.Println("时间范围内所有计划执行均有记录")
+	}
+}
@@ -0,0 +1,666 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/utils"
+)
+
+// 导出格式
+const (
+	exportCrontab = "crontab"
+	exportSystemd = "systemd"
+	exportGHA     = "gha"
+	exportK8s     = "k8s"
+)
+
+var (
+	exportFormat     string
+	exportScheduleID int
+	exportBin        string
+	exportOutput     string
+	exportImage      string
+	exportSecretName string
+)
+
+var scheduleExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "将已启用的定时任务导出为 crontab、systemd 定时器、GitHub Actions 工作流或 Kubernetes CronJob",
+	Example: `  xixun schedule export --format crontab >> /tmp/xixun.cron
+  xixun schedule export --format systemd -o /etc/systemd/system
+  xixun schedule export --format gha -o .github/workflows/schedule.yml
+  xixun schedule export --format k8s --image shadowaura/xixunyunsign:latest | kubectl apply -f -`,
+	Run: func(cmd *cobra.Command, args []string) {
+		exportSchedules()
+	},
+}
+
+func init() {
+	scheduleExportCmd.Flags().StringVar(&exportFormat, "format", exportCrontab, "导出格式(crontab/systemd/gha/k8s)")
+	scheduleExportCmd.Flags().StringVarP(&account, "account", "a", "", "只导出该账号的任务")
+	scheduleExportCmd.Flags().IntVarP(&exportScheduleID, "id", "i", 0, "只导出该任务")
+	scheduleExportCmd.Flags().StringVar(&exportBin, "bin", "", "执行命令时使用的程序路径(默认 crontab/systemd 为当前程序，gha 为 ./xixunyunsign，k8s 为 xixunyunsign)")
+	scheduleExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "输出文件(systemd 为输出目录)，为空时输出到终端")
+	scheduleExportCmd.Flags().StringVar(&exportImage, "image", "shadowaura/xixunyunsign:latest", "[k8s] 容器镜像")
+	scheduleExportCmd.Flags().StringVar(&exportSecretName, "secret_name", "xixun", "[k8s] 保存账号、密码等信息的 Secret 名称")
+
+	ScheduleCmd.AddCommand(scheduleExportCmd)
+}
+
+func exportSchedules() {
+	loc, err := location()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	all, err := utils.LoadSchedules()
+	if err != nil {
+		fmt.Println("查询定时任务失败:", err)
+		return
+	}
+	var tasks []utils.ScheduleTask
+	for _, t := range all {
+		if (account == "" || t.Account == account) && (exportScheduleID == 0 || t.ID == exportScheduleID) {
+			tasks = append(tasks, t)
+		}
+	}
+	if len(tasks) == 0 {
+		fmt.Println("没有可导出的已启用定时任务")
+		return
+	}
+
+	e := &scheduleExporter{format: exportFormat, bin: exportBin, loc: loc}
+	var files map[string]string
+	switch exportFormat {
+	case exportCrontab:
+		files = e.crontab(tasks)
+	case exportSystemd:
+		files = e.systemd(tasks)
+	case exportGHA:
+		files = e.gha(tasks)
+	case exportK8s:
+		files = e.k8s(tasks)
+	default:
+		fmt.Printf("不支持的导出格式: %s(应为 crontab、systemd、gha 或 k8s)\n", exportFormat)
+		return
+	}
+	if err := writeExport(files, exportOutput, exportFormat == exportSystemd); err != nil {
+		fmt.Println("导出定时任务失败:", err)
+		return
+	}
+	for _, note := range e.skipped {
+		fmt.Fprintln(os.Stderr, "已跳过", note)
+	}
+	if exportOutput != "" {
+		fmt.Printf("已导出 %d 个定时任务到 %s\n", len(tasks)-len(e.skipped), exportOutput)
+	}
+}
+
+// writeExport 将导出结果写入文件。dir 为 true 时 output 为目录，每个文件单独写入；
+// output 为空时输出到终端，多个文件之间以文件名分隔。
+func writeExport(files map[string]string, output string, dir bool) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if output != "" && dir {
+		if err := os.MkdirAll(output, 0o755); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(output, name), []byte(files[name]), 0o644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	for i, name := range names {
+		if len(names) > 1 {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "# ==> %s <==\n", name)
+		}
+		fmt.Fprint(w, files[name])
+	}
+	return nil
+}
+
+// exportArg 是导出命令中的一个参数：env 不为空时引用同名环境变量(由 secret 注入)，否则为字面值。
+type exportArg struct {
+	value    string
+	env      string
+	optional bool // secret 可以不设置
+}
+
+func literal(v string) exportArg { return exportArg{value: v} }
+
+// scheduleExporter 将定时任务转换为其他调度器的配置
+type scheduleExporter struct {
+	format string
+	bin    string
+	loc    *time.Location
+
+	accounts []string // 按出现顺序排列的账号，用于区分多个账号的 secret 名称
+	skipped  []string
+}
+
+// skip 记录无法导出的任务
+func (e *scheduleExporter) skip(t utils.ScheduleTask, err error) {
+	e.skipped = append(e.skipped, fmt.Sprintf("[%d] %v", t.ID, err))
+}
+
+// stateless 报告目标环境是否没有本机数据库(每次执行前需要登录)
+func (e *scheduleExporter) stateless() bool {
+	return e.format == exportGHA || e.format == exportK8s
+}
+
+// program 返回执行命令时使用的程序路径
+func (e *scheduleExporter) program() string {
+	if e.bin != "" {
+		return e.bin
+	}
+	switch e.format {
+	case exportGHA:
+		return "./xixunyunsign"
+	case exportK8s:
+		return "xixunyunsign"
+	}
+	if exe, err := os.Executable(); err == nil {
+		return exe
+	}
+	return "xixunyunsign"
+}
+
+// secret 返回账号对应的 secret 引用。第一个账号使用 scheduler.md 中的名称(USERNAME、PASSWORD 等)，
+// 之后的账号依次加上 _2、_3 等后缀。
+func (e *scheduleExporter) secret(name, account string, optional bool) exportArg {
+	idx := -1
+	for i, a := range e.accounts {
+		if a == account {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		idx = len(e.accounts)
+		e.accounts = append(e.accounts, account)
+	}
+	if idx > 0 {
+		name += "_" + strconv.Itoa(idx+1)
+	}
+	return exportArg{env: name, optional: optional}
+}
+
+// commands 返回执行定时任务的命令(不含程序路径)。
+// crontab、systemd 在保存了数据库的本机执行，直接使用数据库中的账号信息，
+// 无法用单独命令表示的任务通过 schedule run 执行；
+// gha、k8s 中没有数据库，先使用 secret 中的账号密码登录，再执行签到或查询。
+func (e *scheduleExporter) commands(t utils.ScheduleTask) ([][]exportArg, error) {
+	var sign utils.SignPayload
+	if t.JobType == utils.JobTypeSign {
+		if err := t.Job("", time.Time{}).Decode(&sign); err != nil {
+			return nil, err
+		}
+	}
+
+	if !e.stateless() {
+		switch {
+		case t.JobType == utils.JobTypeSign && sign.Address != "":
+			refs := signRefs{
+				account:     literal(t.Account),
+				address:     literal(sign.Address),
+				addressName: literal(sign.AddressName),
+				latitude:    literal(sign.Latitude),
+				longitude:   literal(sign.Longitude),
+			}
+			return [][]exportArg{signArgs(refs, sign)}, nil
+		case t.JobType == utils.JobTypeQuery:
+			return [][]exportArg{{literal("query"), literal("-a"), literal(t.Account)}}, nil
+		}
+		return [][]exportArg{{literal("schedule"), literal("run"), literal(strconv.Itoa(t.ID))}}, nil
+	}
+
+	if t.JobType != utils.JobTypeSign && t.JobType != utils.JobTypeQuery {
+		return nil, fmt.Errorf("%s 任务依赖本机数据库或文件，无法在 %s 中执行", t.JobType, e.format)
+	}
+	user := e.secret("USERNAME", t.Account, false)
+	login := []exportArg{literal("login"), literal("-a"), user, literal("-p"), e.secret("PASSWORD", t.Account, false)}
+	if _, schoolID, err := utils.GetCredentials(t.Account); err == nil && schoolID != "" {
+		login = append(login, literal("-i"), literal(schoolID))
+	}
+	cmds := [][]exportArg{login}
+	if t.JobType == utils.JobTypeQuery || sign.Latitude == "" || sign.Longitude == "" {
+		cmds = append(cmds, []exportArg{literal("query"), literal("-a"), user})
+	}
+	if t.JobType == utils.JobTypeSign {
+		refs := signRefs{
+			account:     user,
+			address:     e.secret("ADDRESS", t.Account, false),
+			addressName: e.secret("ADDRESS_NAME", t.Account, false),
+			latitude:    e.secret("LATITUDE", t.Account, false),
+			longitude:   e.secret("LONGITUDE", t.Account, false),
+		}
+		args := signArgs(refs, sign)
+		cmds = append(cmds, append(args, literal("-k"), e.secret("API_KEY_FANGTANG", t.Account, true)))
+	}
+	return cmds, nil
+}
+
+// signRefs 是 sign 命令中可能引用 secret 的参数
+type signRefs struct {
+	account, address, addressName, latitude, longitude exportArg
+}
+
+// signArgs 返回 sign 命令的参数，地址名称与经纬度只在签到参数中设置了时才传入
+func signArgs(refs signRefs, p utils.SignPayload) []exportArg {
+	args := []exportArg{literal("sign"), literal("-a"), refs.account, literal("--address"), refs.address}
+	if p.AddressName != "" {
+		args = append(args, literal("--address_name"), refs.addressName)
+	}
+	if p.Latitude != "" && p.Longitude != "" {
+		args = append(args, literal("--latitude"), refs.latitude, literal("--longitude"), refs.longitude)
+	}
+	for _, f := range []struct{ flag, value string }{
+		{"--province", p.Province}, {"--city", p.City}, {"--comment", p.Comment},
+	} {
+		if f.value != "" {
+			args = append(args, literal(f.flag), literal(f.value))
+		}
+	}
+	if p.Remark != "" && p.Remark != "0" {
+		args = append(args, literal("--remark"), literal(p.Remark))
+	}
+	return args
+}
+
+// taskFields 返回任务的执行时间字段及其所在时区
+func (e *scheduleExporter) taskFields(t utils.ScheduleTask) (utils.CronFields, *time.Location, error) {
+	loc := t.Location(e.loc)
+	if t.IsOneShot() {
+		return utils.OnceFields(t.RunAt.In(loc)), loc, nil
+	}
+	f, err := utils.ParseCronFields(t.CronExpr)
+	if err != nil {
+		return f, nil, err
+	}
+	if f.Location != nil {
+		loc = f.Location
+	}
+	return f, loc, nil
+}
+
+// describe 返回写在导出配置中的任务说明。gha、k8s 的配置通常会提交到仓库，不包含账号。
+func (e *scheduleExporter) describe(t utils.ScheduleTask) string {
+	when := fmt.Sprintf("cron:%q", t.CronExpr)
+	if t.IsOneShot() {
+		when = "一次性:" + t.RunAt.In(t.Location(e.loc)).Format(time.RFC3339)
+	}
+	if e.stateless() {
+		return fmt.Sprintf("[%d] %s %s", t.ID, t.JobType, when)
+	}
+	return fmt.Sprintf("[%d] %s %s %s", t.ID, t.JobType, t.Account, when)
+}
+
+// notes 返回导出后与守护进程中执行时行为不一致之处
+func (e *scheduleExporter) notes(t utils.ScheduleTask, f utils.CronFields) []string {
+	var notes []string
+	if t.IsOneShot() && e.format != exportSystemd {
+		notes = append(notes, "一次性任务：cron 无法指定年份，执行后请删除")
+	}
+	if f.Every != 0 && e.format != exportSystemd {
+		notes = append(notes, fmt.Sprintf("@every %s 已近似为按整点对齐的执行时间", f.Every))
+	}
+	if t.DayPolicy != "" && t.DayPolicy != utils.DayPolicyEveryday {
+		notes = append(notes, fmt.Sprintf("签到日期 %s 无法导出，将按 cron 每次执行", t.DayPolicy))
+	}
+	return notes
+}
+
+// zoneName 返回时区名称，程序所在系统的本地时区返回空字符串
+func zoneName(loc *time.Location) string {
+	if loc == nil || loc == time.Local || loc.String() == "Local" {
+		return ""
+	}
+	return loc.String()
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellWord 返回参数在 shell 中的写法，secret 引用写作 "$NAME"
+func shellWord(a exportArg) string {
+	if a.env != "" {
+		return `"$` + a.env + `"`
+	}
+	if shellSafe.MatchString(a.value) {
+		return a.value
+	}
+	return "'" + strings.ReplaceAll(a.value, "'", `'\''`) + "'"
+}
+
+// shellLine 返回完整的 shell 命令
+func (e *scheduleExporter) shellLine(args []exportArg) string {
+	words := []string{shellWord(literal(e.program()))}
+	for _, a := range args {
+		words = append(words, shellWord(a))
+	}
+	return strings.Join(words, " ")
+}
+
+// secretNames 返回命令中引用的 secret，按首次出现的顺序排列
+func secretNames(cmds [][]exportArg) []exportArg {
+	var names []exportArg
+	seen := make(map[string]bool)
+	for _, args := range cmds {
+		for _, a := range args {
+			if a.env != "" && !seen[a.env] {
+				seen[a.env] = true
+				names = append(names, a)
+			}
+		}
+	}
+	return names
+}
+
+// workingDir 返回数据库所在目录，crontab 与 systemd 在该目录中执行命令
+func workingDir() string {
+	abs, err := filepath.Abs(utils.DBPath)
+	if err != nil {
+		return "."
+	}
+	return filepath.Dir(abs)
+}
+
+// crontab 导出为一个 crontab 文件，时区通过 CRON_TZ 设置
+func (e *scheduleExporter) crontab(tasks []utils.ScheduleTask) map[string]string {
+	var b strings.Builder
+	b.WriteString("# 由 xixunyunsign schedule export --format crontab 生成\n")
+	b.WriteString("# CRON_TZ 需要 cronie 等支持该变量的 cron，否则请将时间换算为系统时区\n")
+	dir := shellWord(literal(workingDir()))
+	tz := ""
+	for _, t := range tasks {
+		f, loc, err := e.taskFields(t)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		spec, err := f.Crontab()
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		cmds, err := e.commands(t)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n# %s\n", e.describe(t))
+		for _, n := range e.notes(t, f) {
+			fmt.Fprintf(&b, "# 注意: %s\n", n)
+		}
+		if name := zoneName(loc); name != tz {
+			fmt.Fprintf(&b, "CRON_TZ=%s\n", name)
+			tz = name
+		}
+		// crontab 中 % 表示换行，需要转义
+		line := "cd " + dir + " && " + e.shellLine(cmds[0])
+		fmt.Fprintf(&b, "%s %s\n", spec, strings.ReplaceAll(line, "%", `\%`))
+	}
+	return map[string]string{"xixun.cron": b.String()}
+}
+
+// systemdWord 返回参数在 ExecStart 中的写法
+func systemdWord(s string) string {
+	s = strings.NewReplacer("%", "%%", "$", "$$").Replace(s)
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// systemd 为每个任务导出一对 .service 与 .timer 单元
+func (e *scheduleExporter) systemd(tasks []utils.ScheduleTask) map[string]string {
+	files := make(map[string]string)
+	for _, t := range tasks {
+		f, loc, err := e.taskFields(t)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		var timer []string
+		switch {
+		case t.IsOneShot():
+			timer = []string{"OnCalendar=" + strings.TrimSpace(t.RunAt.In(loc).Format("2006-01-02 15:04:05")+" "+zoneName(loc))}
+		case f.Every != 0:
+			secs := strconv.Itoa(int(f.Every / time.Second))
+			timer = []string{"OnActiveSec=" + secs + "s", "OnUnitActiveSec=" + secs + "s"}
+		default:
+			cals, err := f.OnCalendar()
+			if err != nil {
+				e.skip(t, err)
+				continue
+			}
+			for _, cal := range cals {
+				timer = append(timer, "OnCalendar="+strings.TrimSpace(cal+" "+zoneName(loc)))
+			}
+		}
+		if t.MissedPolicy == utils.MissedPolicyRunOnce && f.Every == 0 {
+			// 开机后补执行停机期间错过的一次；Persistent 只对 OnCalendar 生效，
+			// @every 任务开机后由 OnActiveSec 重新计时
+			timer = append(timer, "Persistent=true")
+		}
+		cmds, err := e.commands(t)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		words := []string{systemdWord(e.program())}
+		for _, a := range cmds[0] {
+			words = append(words, systemdWord(a.value))
+		}
+
+		unit := fmt.Sprintf("xixun-schedule-%d", t.ID)
+		var desc strings.Builder
+		fmt.Fprintf(&desc, "[Unit]\nDescription=xixunyunsign 定时任务 %s\n", e.describe(t))
+		for _, n := range e.notes(t, f) {
+			fmt.Fprintf(&desc, "# 注意: %s\n", n)
+		}
+		files[unit+".service"] = fmt.Sprintf("%s\n[Service]\nType=oneshot\nWorkingDirectory=%s\nExecStart=%s\n",
+			desc.String(), workingDir(), strings.Join(words, " "))
+		files[unit+".timer"] = fmt.Sprintf("%s\n[Timer]\n%s\n\n[Install]\nWantedBy=timers.target\n",
+			desc.String(), strings.Join(timer, "\n"))
+	}
+	return files
+}
+
+// utcSchedules 返回任务在 UTC 下的 cron 表达式，GitHub Actions 只支持 UTC
+func (e *scheduleExporter) utcSchedules(t utils.ScheduleTask, f utils.CronFields, loc *time.Location) ([]string, []string, error) {
+	var notes []string
+	shifted := []utils.CronFields{utils.OnceFields(t.RunAt.UTC())}
+	if !t.IsOneShot() {
+		now := time.Now().In(loc)
+		_, offset := now.Zone()
+		_, jan := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc).Zone()
+		_, jul := time.Date(now.Year(), 7, 1, 0, 0, 0, 0, loc).Zone()
+		if jan != jul {
+			notes = append(notes, fmt.Sprintf("%s 有夏令时，以下时间按当前的 UTC 偏移换算，切换夏令时后需要重新导出", loc))
+		}
+		var err error
+		if shifted, err = f.Shift(-time.Duration(offset) * time.Second); err != nil {
+			return nil, nil, err
+		}
+	}
+	var specs []string
+	for _, s := range shifted {
+		spec, err := s.Crontab()
+		if err != nil {
+			return nil, nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, notes, nil
+}
+
+// gha 导出为一个 GitHub Actions 工作流，每个任务对应一个 job，只在自己的 cron 触发时执行
+func (e *scheduleExporter) gha(tasks []utils.ScheduleTask) map[string]string {
+	var crons []string
+	cronTasks := make(map[string][]string)
+	var jobs strings.Builder
+	for _, t := range tasks {
+		f, loc, err := e.taskFields(t)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		specs, tzNotes, err := e.utcSchedules(t, f, loc)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		cmds, err := e.commands(t)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+
+		conds := []string{"github.event_name == 'workflow_dispatch'"}
+		for _, spec := range specs {
+			if _, ok := cronTasks[spec]; !ok {
+				crons = append(crons, spec)
+			}
+			cronTasks[spec] = append(cronTasks[spec], strconv.Itoa(t.ID))
+			conds = append(conds, fmt.Sprintf("github.event.schedule == '%s'", spec))
+		}
+		fmt.Fprintf(&jobs, "\n  schedule-%d:\n", t.ID)
+		fmt.Fprintf(&jobs, "    # %s %s\n", e.describe(t), zoneName(loc))
+		for _, n := range append(tzNotes, e.notes(t, f)...) {
+			fmt.Fprintf(&jobs, "    # 注意: %s\n", n)
+		}
+		fmt.Fprintf(&jobs, "    if: %s\n", strings.Join(conds, " || "))
+		jobs.WriteString(`    runs-on: ubuntu-22.04
+    steps:
+      - name: Checkout repository
+        uses: actions/checkout@v4
+
+      - name: Set up Go
+        uses: actions/setup-go@v4
+        with:
+          go-version-file: go.mod
+
+      - name: Build
+        run: go build -o xixunyunsign .
+
+`)
+		fmt.Fprintf(&jobs, "      - name: Run %s\n        env:\n", t.JobType)
+		for _, s := range secretNames(cmds) {
+			fmt.Fprintf(&jobs, "          %s: ${{ secrets.%s }}\n", s.env, s.env)
+		}
+		jobs.WriteString("        run: |\n")
+		for _, args := range cmds {
+			fmt.Fprintf(&jobs, "          %s\n", e.shellLine(args))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# 由 xixunyunsign schedule export --format gha 生成\n")
+	b.WriteString("# GitHub Actions 的 cron 使用 UTC 时间，以下时间已由任务的时区换算，实际执行可能延迟几分钟\n")
+	b.WriteString("name: Xixun schedule\n\non:\n  schedule:\n")
+	for _, spec := range crons {
+		fmt.Fprintf(&b, "    - cron: '%s' # 任务 %s\n", spec, strings.Join(cronTasks[spec], ", "))
+	}
+	b.WriteString("  workflow_dispatch: # 手动触发\n\njobs:")
+	b.WriteString(jobs.String())
+	return map[string]string{"xixun-schedule.yml": b.String()}
+}
+
+// k8s 为每个任务导出一个 CronJob，账号、密码等从 Secret 中读取
+func (e *scheduleExporter) k8s(tasks []utils.ScheduleTask) map[string]string {
+	var docs []string
+	for _, t := range tasks {
+		f, loc, err := e.taskFields(t)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		spec, err := f.Crontab()
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+		cmds, err := e.commands(t)
+		if err != nil {
+			e.skip(t, err)
+			continue
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "# %s\n", e.describe(t))
+		for _, n := range e.notes(t, f) {
+			fmt.Fprintf(&b, "# 注意: %s\n", n)
+		}
+		fmt.Fprintf(&b, `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: xixun-schedule-%d
+  labels:
+    app.kubernetes.io/name: xixunyunsign
+spec:
+  schedule: "%s"
+`, t.ID, spec)
+		if name := zoneName(loc); name != "" {
+			fmt.Fprintf(&b, "  timeZone: %s\n", name)
+		}
+		fmt.Fprintf(&b, `  concurrencyPolicy: Forbid
+  jobTemplate:
+    spec:
+      backoffLimit: 2
+      template:
+        spec:
+          restartPolicy: Never
+          containers:
+            - name: xixunyunsign
+              image: %s
+              command: ["/bin/sh", "-c"]
+              args:
+                - |
+                  set -e
+`, exportImage)
+		for _, args := range cmds {
+			fmt.Fprintf(&b, "                  %s\n", e.shellLine(args))
+		}
+		b.WriteString("              env:\n")
+		for _, s := range secretNames(cmds) {
+			fmt.Fprintf(&b, `                - name: %s
+                  valueFrom:
+                    secretKeyRef:
+                      name: %s
+                      key: %s
+`, s.env, exportSecretName, s.env)
+			if s.optional {
+				b.WriteString("                      optional: true\n")
+			}
+		}
+		docs = append(docs, b.String())
+	}
+	return map[string]string{"xixun-cronjobs.yaml": strings.Join(docs, "---\n")}
+}
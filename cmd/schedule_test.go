@@ -0,0 +1,184 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xixunyunsign/cmd"
+	"xixunyunsign/notify"
+	"xixunyunsign/utils"
+)
+
+func TestScheduleRunQueryJob(t *testing.T) {
+	useTempDB(t)
+	resetFlags(t, cmd.ScheduleCmd)
+	require.NoError(t, utils.SaveUser("user", "pass", "tok", "", "", "", "", "张三", 7, "", "", "2022", "2025"))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/signin40/homepage", r.URL.Path)
+		w.Write([]byte(`{"code":20000,"message":"ok","data":{"sign_resources_info":{"mid_sign_latitude":"32.05","mid_sign_longitude":118.79}}}`))
+	}))
+	defer ts.Close()
+	cmd.APIBaseURL = ts.URL
+
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeQuery, "--cron", "0 7 * * 1", "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	tasks, err := utils.ListSchedules()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, utils.JobTypeQuery, tasks[0].JobType)
+
+	cmd.ScheduleCmd.SetArgs([]string{"run", "1"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+
+	lat, lng, err := utils.GetCoordinates("user")
+	require.NoError(t, err)
+	assert.Equal(t, "32.05", lat)
+	assert.Equal(t, "118.79", lng)
+
+	runs, err := utils.QueryJobRuns(utils.JobRunFilter{ScheduleID: 1})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, utils.JobOutcomeSuccess, runs[0].Outcome)
+	assert.Equal(t, utils.JobTriggerManual, runs[0].Trigger)
+
+	// 报告任务缺少内容时不应添加
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeReport, "--cron", "0 20 * * 5", "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	tasks, err = utils.ListSchedules()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+}
+
+func TestScheduleExport(t *testing.T) {
+	useTempDB(t)
+	resetFlags(t, cmd.ScheduleCmd)
+	dir := t.TempDir()
+
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeSign, "--cron", "30 8 * * 1-5", "--address", "江苏省南京市玄武区北京东路41号", "--address_name", "南京市政府", "--timezone", "Asia/Shanghai", "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeReport, "--content", "周报", "--cron", "0 20 * * 5", "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+
+	export := func(format string) string {
+		out := filepath.Join(dir, format)
+		cmd.ScheduleCmd.SetArgs([]string{"export", "--format", format, "--bin", "xixun", "-o", out})
+		require.NoError(t, cmd.ScheduleCmd.Execute())
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	crontab := export("crontab")
+	assert.Contains(t, crontab, "CRON_TZ=Asia/Shanghai\n")
+	assert.Contains(t, crontab, "30 8 * * 1-5 cd ")
+	assert.Contains(t, crontab, "&& xixun sign -a user --address '江苏省南京市玄武区北京东路41号' --address_name '南京市政府'\n")
+	assert.Contains(t, crontab, "0 20 * * 5 cd ")
+	assert.Contains(t, crontab, "&& xixun schedule run 2\n")
+
+	// GitHub Actions 使用 UTC，账号等信息从 secret 读取，报告任务依赖本机文件无法导出
+	gha := export("gha")
+	assert.Contains(t, gha, "- cron: '30 0 * * 1-5'")
+	assert.Contains(t, gha, "github.event.schedule == '30 0 * * 1-5'")
+	assert.Contains(t, gha, "PASSWORD: ${{ secrets.PASSWORD }}")
+	assert.Contains(t, gha, `xixun login -a "$USERNAME" -p "$PASSWORD"`)
+	assert.Contains(t, gha, `xixun sign -a "$USERNAME" --address "$ADDRESS" --address_name "$ADDRESS_NAME" -k "$API_KEY_FANGTANG"`)
+	assert.NotContains(t, gha, "user")
+	assert.NotContains(t, gha, "schedule-2")
+	assert.Contains(t, gha, "uses: actions/checkout@v4")
+	assert.Contains(t, gha, "go-version-file: go.mod")
+
+	k8s := export("k8s")
+	assert.Contains(t, k8s, `schedule: "30 8 * * 1-5"`)
+	assert.Contains(t, k8s, "timeZone: Asia/Shanghai")
+	assert.Contains(t, k8s, "key: PASSWORD")
+
+	cmd.ScheduleCmd.SetArgs([]string{"export", "--format", "systemd", "--bin", "xixun", "-o", filepath.Join(dir, "units")})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	timer, err := os.ReadFile(filepath.Join(dir, "units", "xixun-schedule-1.timer"))
+	require.NoError(t, err)
+	assert.Contains(t, string(timer), "OnCalendar=Mon..Fri *-*-* 08:30:00 Asia/Shanghai\n")
+	service, err := os.ReadFile(filepath.Join(dir, "units", "xixun-schedule-2.service"))
+	require.NoError(t, err)
+	assert.Contains(t, string(service), "ExecStart=xixun schedule run 2\n")
+
+	// Persistent 只对 OnCalendar 生效，@every 任务不写入
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeQuery, "--cron", "@every 12h", "--missed_policy", utils.MissedPolicyRunOnce, "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeQuery, "--cron", "0 7 * * 1", "--missed_policy", utils.MissedPolicyRunOnce, "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	cmd.ScheduleCmd.SetArgs([]string{"export", "--format", "systemd", "--bin", "xixun", "-o", filepath.Join(dir, "units")})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	timer, err = os.ReadFile(filepath.Join(dir, "units", "xixun-schedule-3.timer"))
+	require.NoError(t, err)
+	assert.Contains(t, string(timer), "OnUnitActiveSec=43200s\n")
+	assert.NotContains(t, string(timer), "Persistent=true")
+	timer, err = os.ReadFile(filepath.Join(dir, "units", "xixun-schedule-4.timer"))
+	require.NoError(t, err)
+	assert.Contains(t, string(timer), "Persistent=true\n")
+}
+
+func TestScheduleRunReportCommentJob(t *testing.T) {
+	useTempDB(t)
+	resetFlags(t, cmd.ScheduleCmd)
+	require.NoError(t, utils.SaveUser("user", "pass", "tok", "", "", "", "", "张三", 7, "", "", "2022", "2025"))
+
+	reports := `{"id":1,"business_type":"week","start_date":"2026/10/05","end_date":"2026/10/11","comment":"已阅","comment_name":"李老师"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/Reports/StudentList", r.URL.Path)
+		w.Write([]byte(`{"code":20000,"data":{"list":[` + reports + `]}}`))
+	}))
+	defer ts.Close()
+	cmd.APIBaseURL = ts.URL
+
+	var bodies []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload notify.WebhookPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		assert.Equal(t, notify.EventReportCommented, payload.Event)
+		bodies = append(bodies, payload.Body)
+	}))
+	defer hook.Close()
+	t.Setenv(cmd.EnvNotifyURLs, "webhook://"+strings.TrimPrefix(hook.URL, "http://")+"/hook")
+
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeReportComment, "--cron", "0 */2 * * *", "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	run := func() {
+		cmd.ScheduleCmd.SetArgs([]string{"run", "1"})
+		require.NoError(t, cmd.ScheduleCmd.Execute())
+	}
+
+	// 第一次只记录现有的批阅
+	run()
+	assert.Empty(t, bodies)
+
+	// 新的批阅发送通知，已通知过的不再重复
+	reports += `,{"id":2,"business_type":"week","start_date":"2026/10/12","end_date":"2026/10/18","comment":"内容充实","comment_name":"李老师"}`
+	run()
+	run()
+	require.Len(t, bodies, 1)
+	assert.Equal(t, "张三(user) 的周报(2026/10/12 - 2026/10/18)收到 李老师 的批阅：\n内容充实", bodies[0])
+}
+
+func TestScheduleAddRejectsPastAt(t *testing.T) {
+	useTempDB(t)
+	resetFlags(t, cmd.ScheduleCmd)
+
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeQuery, "--at", time.Now().Add(-time.Minute).Format(time.RFC3339), "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	cmd.ScheduleCmd.SetArgs([]string{"add", "-a", "user", "--type", utils.JobTypeQuery, "--at", time.Now().Add(time.Hour).Format(time.RFC3339), "--preview", "0"})
+	require.NoError(t, cmd.ScheduleCmd.Execute())
+	tasks, err := utils.ListSchedules()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.True(t, tasks[0].IsOneShot())
+}
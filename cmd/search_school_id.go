@@ -25,7 +25,7 @@ var SchoolSearchIDCmd = &cobra.Command{
 
 		// 如果学校数据表为空，则获取并保存学校数据
 		if isEmpty {
-			err := utils.FetchAndSaveSchoolData()
+			err := utils.FetchAndSaveSchoolData(newClient())
 			if err != nil {
 				log.Printf("Error fetching and saving school data: %v", err)
 				return
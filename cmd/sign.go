@@ -1,23 +1,16 @@
 package cmd
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/json"
-	"encoding/pem"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"xixunyunsign/utils"
+	"xixunyunsign/xixunyun"
 )
 
 var (
@@ -49,204 +42,192 @@ func init() {
 	SignCmd.Flags().StringVarP(&longitude, "longitude", "", "", "经度")
 	SignCmd.Flags().StringVarP(&remark, "remark", "", "0", "备注")
 	SignCmd.Flags().StringVarP(&comment, "comment", "", "", "评论")
-	SignCmd.Flags().StringVarP(&province, "province", "p", "", "省份")
-	SignCmd.Flags().StringVarP(&city, "city", "c", "", "城市")
+	SignCmd.Flags().StringVarP(&province, "province", "p", "", "省份(为空时从地址中提取)")
+	SignCmd.Flags().StringVarP(&city, "city", "c", "", "城市(为空时从地址中提取)")
 	SignCmd.Flags().BoolVarP(&debug, "debug", "d", false, "启用调试模式") // 添加 debug 标志
 	SignCmd.Flags().StringVarP(&secret_key, "secret_key", "k", "", "server酱密钥")
+	SignCmd.Flags().BoolVar(&noRelogin, "no_relogin", false, "token 失效时不使用已保存的密码自动重新登录")
+	addRetryFlags(SignCmd)
 
 	// 标记必需的标志
 	SignCmd.MarkFlagRequired("account")
 	SignCmd.MarkFlagRequired("address")
 }
 
-// signIn 执行签到逻辑
-func signIn() {
-	// 获取用户信息
-	token, dbLatitude, dbLongitude, err := utils.GetUser(account)
-	if err != nil || token == "" {
-		if debug {
-			fmt.Printf("获取用户信息失败: %v\n", err)
-		}
-		fmt.Println("未找到该账号的 token，请先登录。")
-		return
-	}
+// signParams 描述一次签到所需的参数，经纬度为空时使用数据库中保存的值。
+type signParams struct {
+	Account     string
+	Address     string
+	AddressName string
+	Latitude    string
+	Longitude   string
+	Province    string
+	City        string
+	Remark      string
+	Comment     string
+}
 
-	// 如果命令行未提供 latitude 和 longitude，则使用数据库中的值
-	if latitude == "" {
-		latitude = dbLatitude
-	}
-	if longitude == "" {
-		longitude = dbLongitude
+// newSignParams 由签到任务的参数构造 signParams
+func newSignParams(account string, p utils.SignPayload) signParams {
+	return signParams{
+		Account:     account,
+		Address:     p.Address,
+		AddressName: p.AddressName,
+		Latitude:    p.Latitude,
+		Longitude:   p.Longitude,
+		Province:    p.Province,
+		City:        p.City,
+		Remark:      p.Remark,
+		Comment:     p.Comment,
 	}
+}
 
-	if latitude == "" || longitude == "" {
-		fmt.Println("未提供经纬度信息，且数据库中不存在，请先查询签到信息或手动提供经纬度。")
-		return
+// payload 返回保存到定时任务或重试队列中的签到参数
+func (p signParams) payload() utils.SignPayload {
+	return utils.SignPayload{
+		Address:     p.Address,
+		AddressName: p.AddressName,
+		Latitude:    p.Latitude,
+		Longitude:   p.Longitude,
+		Province:    p.Province,
+		City:        p.City,
+		Remark:      p.Remark,
+		Comment:     p.Comment,
 	}
+}
 
-	// 使用公钥加密 latitude 和 longitude
-	encryptedLatitude, err := rsaEncrypt([]byte(latitude))
-	if err != nil {
-		if debug {
-			fmt.Printf("加密纬度失败: %v\n", err)
-		}
-		fmt.Println("加密纬度失败:", err)
-		return
+// errNoCoordinates 表示既未提供经纬度、数据库中也没有保存。
+var errNoCoordinates = errors.New("未提供经纬度信息，且数据库中不存在，请先查询签到信息或手动提供经纬度。")
+
+// performSign 执行一次签到并将结果写入签到记录，trigger 为触发来源
+// (utils.SignTriggerManual 等)。返回的 signParams 为实际使用的参数。
+func performSign(ctx context.Context, p signParams, trigger string) (signParams, *xixunyun.SignInResponse, error) {
+	start := time.Now()
+	resp, err := doSign(ctx, &p)
+
+	entry := utils.SignLog{
+		Account:   p.Account,
+		SignedAt:  start,
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+		Address:   p.Address,
+		Status:    utils.SignStatusSuccess,
+		Code:      xixunyun.CodeSuccess,
+		Duration:  time.Since(start),
+		Trigger:   trigger,
+	}
+	if resp != nil {
+		entry.Message = resp.Message
 	}
-
-	encryptedLongitude, err := rsaEncrypt([]byte(longitude))
 	if err != nil {
-		if debug {
-			fmt.Printf("加密经度失败: %v\n", err)
+		entry.Status = utils.SignStatusFailed
+		if errors.Is(err, xixunyun.ErrAlreadySigned) {
+			entry.Status = utils.SignStatusAlreadySigned
 		}
-		fmt.Println("加密经度失败:", err)
-		return
-	}
-
-	// 从 address 提取 province 和 city
-	if address != "" {
-		extractedProvince, extractedCity, err := extractProvinceAndCity(address)
-		if err != nil {
-			if debug {
-				fmt.Printf("提取省份和城市失败: %v\n", err)
-			}
-			fmt.Println("地址格式不正确，无法提取省份和城市:", err)
-			return
+		entry.Code = 0
+		entry.Message = err.Error()
+		entry.ErrorKind = string(xixunyun.KindOf(err))
+		var apiErr *xixunyun.APIError
+		if errors.As(err, &apiErr) {
+			entry.Code = apiErr.Code
+			entry.Message = apiErr.Message
 		}
-		province = extractedProvince
-		city = extractedCity
 	}
-
-	apiURL := "https://api.xixunyun.com/signin_rsa"
-
-	data := url.Values{}
-	data.Set("address", address)
-	data.Set("province", province)
-	data.Set("city", city)
-	data.Set("latitude", encryptedLatitude)
-	data.Set("longitude", encryptedLongitude)
-	data.Set("remark", remark)
-	data.Set("comment", comment)
-	data.Set("address_name", address_name)
-	data.Set("change_sign_resource", "0")
-
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(data.Encode()))
-	if err != nil {
-		if debug {
-			fmt.Printf("创建请求失败: %v\n", err)
-		}
-		fmt.Println("创建请求失败:", err)
-		return
+	if _, logErr := utils.RecordSignLog(entry); logErr != nil {
+		fmt.Println("记录签到结果失败:", logErr)
 	}
+	return p, resp, err
+}
 
-	query := req.URL.Query()
-	query.Add("token", token)
-	query.Add("from", "app")
-	query.Add("version", "5.1.3")
-	query.Add("platform", "android")
-	query.Add("entrance_year", "0")
-	query.Add("graduate_year", "0")
-	query.Add("school_id", school_id) // 确保 school_id 已正确获取
-	req.URL.RawQuery = query.Encode()
-
-	req.Header.Set("User-Agent", "okhttp/3.8.0")
-	req.Header.Set("Accept-Encoding", "gzip")
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	// req.Header.Set("Cookie", "PHPSESSID=qkc555lu6050h43e204crialf0") // 注释掉不必要的 Cookie
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		if debug {
-			fmt.Printf("发送 HTTP 请求失败: %v\n", err)
+// doSign 补全签到参数并调用签到接口。
+func doSign(ctx context.Context, p *signParams) (*xixunyun.SignInResponse, error) {
+	// 如果未提供 latitude 和 longitude，则使用数据库中的值
+	if p.Latitude == "" || p.Longitude == "" {
+		dbLatitude, dbLongitude, err := utils.GetCoordinates(p.Account)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户信息失败: %w", err)
 		}
-		fmt.Println("请求失败:", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		if debug {
-			fmt.Printf("读取响应体失败: %v\n", err)
+		if p.Latitude == "" {
+			p.Latitude = dbLatitude
+		}
+		if p.Longitude == "" {
+			p.Longitude = dbLongitude
 		}
-		fmt.Println("读取响应体失败:", err)
-		return
 	}
-
-	if debug {
-		fmt.Printf("响应状态码: %d\n", resp.StatusCode)
-		fmt.Printf("响应体: %s\n", string(body))
+	if p.Latitude == "" || p.Longitude == "" {
+		return nil, errNoCoordinates
 	}
 
-	var result map[string]interface{}
-	err = json.Unmarshal(body, &result)
-	if err != nil {
-		if debug {
-			fmt.Printf("解析 JSON 失败: %v\n", err)
+	// 未指定 province 或 city 时从 address 提取
+	if p.Address != "" && (p.Province == "" || p.City == "") {
+		extractedProvince, extractedCity, err := extractProvinceAndCity(p.Address)
+		if err != nil {
+			return nil, err
 		}
-		fmt.Println("解析响应数据失败:", err)
-		return
-	}
-
-	// 检查响应码是否为 20000
-	if code, ok := result["code"].(float64); !ok || code != 20000 {
-		if debug {
-			fmt.Printf("签到失败，响应内容: %v\n", result)
+		if p.Province == "" {
+			p.Province = extractedProvince
 		}
-		fmt.Println("签到失败:", result["message"])
-		if secret_key != "" {
-			resultStr, err := json.Marshal(result)
-			if err != nil {
-				println("json解析错误")
-			}
-			PushMsgToWechat("签到失败", result["message"].(string)+"\r\n"+"错误详细信息："+string(resultStr), "9", secret_key)
+		if p.City == "" {
+			p.City = extractedCity
 		}
-		return
 	}
 
-	fmt.Println("签到成功！")
-
-	if secret_key != "" {
-		latitude, longitude, err = utils.GetCoordinates(account)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		PushMsgToWechat("签到成功", result["message"].(string)+"\n签到地址（使用高德地图查询）为"+latitude+"\n"+longitude, "9", secret_key)
-	}
+	// 经纬度由客户端使用公钥加密后提交，token 失效时自动重新登录
+	return newSession(p.Account).SignIn(ctx, xixunyun.SignInRequest{
+		Address:     p.Address,
+		AddressName: p.AddressName,
+		Province:    p.Province,
+		City:        p.City,
+		Latitude:    p.Latitude,
+		Longitude:   p.Longitude,
+		Remark:      p.Remark,
+		Comment:     p.Comment,
+	})
 }
 
-// rsaEncrypt 使用提供的公钥对数据进行加密
-func rsaEncrypt(origData []byte) (string, error) {
-	publicKey := `-----BEGIN PUBLIC KEY-----
-MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQDlYsiV3DsG+t8OFMLyhdmG2P2J
-4GJwmwb1rKKcDZmTxEphPiYTeFIg4IFEiqDCATAPHs8UHypphZTK6LlzANyTzl9L
-jQS6BYVQk81LhQ29dxyrXgwkRw9RdWaMPtcXRD4h6ovx6FQjwQlBM5vaHaJOHhEo
-rHOSyd/deTvcS+hRSQIDAQAB
------END PUBLIC KEY-----`
-
-	block, _ := pem.Decode([]byte(publicKey))
-	if block == nil {
-		return "", errors.New("公钥解码失败")
+// signIn 执行签到逻辑
+func signIn() {
+	used, resp, err := performSign(context.Background(), signParams{
+		Account:     account,
+		Address:     address,
+		AddressName: address_name,
+		Latitude:    latitude,
+		Longitude:   longitude,
+		Province:    province,
+		City:        city,
+		Remark:      remark,
+		Comment:     comment,
+	}, utils.SignTriggerManual)
+	if errors.Is(err, xixunyun.ErrAlreadySigned) {
+		fmt.Println("今日已签到，无需重复签到。")
+		return
 	}
-
-	pubInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
-		return "", err
-	}
-	pub, ok := pubInterface.(*rsa.PublicKey)
-	if !ok {
-		return "", errors.New("解析公钥类型失败")
+		if debug {
+			fmt.Printf("签到请求失败: %#v\n", err)
+		}
+		fmt.Println("签到失败:", describeError(err))
+		item, handled := enqueueSignRetry(used, 0, err)
+		if handled && item.Status != utils.RetryStatusPending {
+			fmt.Printf("%s，不再重试。\n", retryGaveUpReason(item.Status))
+			return // 已发送放弃重试的通知
+		}
+		var note string
+		if handled {
+			note = fmt.Sprintf("已加入重试队列(ID %d)，将于 %s 重试，截止 %s。", item.ID,
+				item.NextAttemptAt.Local().Format("15:04:05"), item.Deadline.Local().Format("15:04"))
+			fmt.Println(note + "请保持守护进程运行，或稍后执行 `xixun retry run`。")
+		}
+		sendNotify(context.Background(), secret_key, signFailureMessage(used, err, note))
+		return
 	}
 
-	encryptedData, err := rsa.EncryptPKCS1v15(rand.Reader, pub, origData)
-	if err != nil {
-		return "", err
+	if debug {
+		fmt.Printf("响应数据: %s\n", string(resp.Data))
 	}
+	fmt.Println("签到成功！")
 
-	// 使用 base64 编码
-	encryptedString := base64.StdEncoding.EncodeToString(encryptedData)
-	return encryptedString, nil
+	sendNotify(context.Background(), secret_key, signSuccessMessage(used, resp))
 }
 
 // extractProvinceAndCity 从地址中提取省份和城市
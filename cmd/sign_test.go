@@ -0,0 +1,107 @@
+package cmd_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xixunyunsign/cmd"
+	"xixunyunsign/utils"
+)
+
+// useTempDB 将数据库指向临时文件
+func useTempDB(t *testing.T) {
+	t.Helper()
+	utils.CloseDB()
+	utils.DBPath = filepath.Join(t.TempDir(), "config.db")
+	require.NoError(t, utils.InitDB())
+	t.Cleanup(func() { utils.CloseDB() })
+}
+
+// resetFlags 将命令及其子命令的参数恢复为默认值，测试结束后再恢复一次。
+// 参数绑定在包级变量上，不恢复时上一次 Execute 的取值会带到之后的调用中。
+func resetFlags(t *testing.T, c *cobra.Command) {
+	t.Helper()
+	var reset func(c *cobra.Command)
+	reset = func(c *cobra.Command) {
+		for _, fs := range []*pflag.FlagSet{c.Flags(), c.PersistentFlags()} {
+			fs.VisitAll(func(f *pflag.Flag) {
+				if v, ok := f.Value.(pflag.SliceValue); ok {
+					require.NoError(t, v.Replace(nil))
+				} else {
+					require.NoError(t, f.Value.Set(f.DefValue), f.Name)
+				}
+				f.Changed = false
+			})
+		}
+		for _, sub := range c.Commands() {
+			reset(sub)
+		}
+	}
+	reset(c)
+	t.Cleanup(func() { reset(c) })
+}
+
+func TestSignRecordsHistory(t *testing.T) {
+	useTempDB(t)
+	resetFlags(t, cmd.SignCmd)
+	require.NoError(t, utils.SaveUser("user", "pass", "tok", "32.05", "118.79", "", "", "张三", 7, "", "", "2022", "2025"))
+
+	signed := 0
+	var provinces, cities []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/signin_rsa", r.URL.Path)
+		signed++
+		provinces = append(provinces, r.FormValue("province"))
+		cities = append(cities, r.FormValue("city"))
+		switch signed {
+		case 1:
+			w.Write([]byte(`{"code":20000,"message":"签到成功"}`))
+			return
+		case 3:
+			w.Write([]byte(`{"code":40000,"message":"今日已签到"}`))
+			return
+		}
+		w.Write([]byte(`{"code":40000,"message":"当前位置不在签到范围内"}`))
+	}))
+	defer ts.Close()
+	cmd.APIBaseURL = ts.URL
+
+	for i := 0; i < 3; i++ {
+		args := []string{"-a", "user", "--address", "江苏省南京市玄武区北京东路41号"}
+		if i == 2 {
+			args = append(args, "--city", "南京")
+		}
+		cmd.SignCmd.SetArgs(args)
+		require.NoError(t, cmd.SignCmd.Execute())
+	}
+	assert.Equal(t, 3, signed)
+	// 未指定的省份、城市从地址中提取，指定的保持不变
+	assert.Equal(t, []string{"江苏省", "江苏省", "江苏省"}, provinces)
+	assert.Equal(t, []string{"南京市", "南京市", "南京"}, cities)
+
+	logs, err := utils.QuerySignLogs(utils.SignLogFilter{Account: "user"})
+	require.NoError(t, err)
+	require.Len(t, logs, 3)
+
+	failed, err := utils.QuerySignLogs(utils.SignLogFilter{Account: "user", Status: utils.SignStatusFailed})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, 40000, failed[0].Code)
+	assert.Equal(t, "out_of_range", failed[0].ErrorKind)
+	assert.Equal(t, utils.SignTriggerManual, failed[0].Trigger)
+	assert.Equal(t, "32.05", failed[0].Latitude)
+	assert.Equal(t, "江苏省南京市玄武区北京东路41号", failed[0].Address)
+
+	// 今日已签到不记为失败
+	already, err := utils.QuerySignLogs(utils.SignLogFilter{Account: "user", Status: utils.SignStatusAlreadySigned})
+	require.NoError(t, err)
+	require.Len(t, already, 1)
+	assert.Equal(t, "今日已签到", already[0].Message)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/notify"
+	"xixunyunsign/utils"
+)
+
+var (
+	summaryMonth  string
+	summaryNotify bool
+)
+
+// historySummaryCmd 汇总一个月的签到情况
+var historySummaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "汇总账号一个月每天的签到情况，可发送到通知渠道(邮件中为 HTML 表格)",
+	Example: `  xixun history summary -a 账号 --month 2026-10
+  xixun history summary -a 账号 --notify`,
+	Run: func(cmd *cobra.Command, args []string) {
+		showSummary()
+	},
+}
+
+func init() {
+	historySummaryCmd.Flags().StringVarP(&account, "account", "a", "", "账号")
+	historySummaryCmd.Flags().StringVarP(&summaryMonth, "month", "m", "", "月份(格式为2006-01，默认为本月)")
+	historySummaryCmd.Flags().BoolVar(&summaryNotify, "notify", false, "同时发送到账号的通知渠道")
+	historySummaryCmd.Flags().StringVarP(&secret_key, "secret_key", "k", "", "server酱密钥(与 --notify 一起使用)")
+	historySummaryCmd.MarkFlagRequired("account")
+	HistoryCmd.AddCommand(historySummaryCmd)
+}
+
+func showSummary() {
+	loc, err := location()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	month := time.Now().In(loc)
+	if summaryMonth != "" {
+		if month, err = time.ParseInLocation("2006-01", summaryMonth, loc); err != nil {
+			fmt.Println("月份格式不正确:", err)
+			return
+		}
+	}
+	days, err := utils.MonthlyAttendance(account, month)
+	if err != nil {
+		fmt.Println("查询签到记录失败:", err)
+		return
+	}
+	if len(days) == 0 {
+		fmt.Println("该月份还没有开始。")
+		return
+	}
+	msg := attendanceSummaryMessage(account, month, days)
+	fmt.Println(renderMessage(msg).Body)
+	fmt.Println()
+	for _, row := range msg.Table {
+		fmt.Println(strings.Join(row, "\t"))
+	}
+	if summaryNotify {
+		sendNotify(context.Background(), secret_key, msg)
+	}
+}
+
+var weekdayNames = []string{"日", "一", "二", "三", "四", "五", "六"}
+
+// attendanceSummaryMessage 构造月度考勤汇总的通知，模板数据为统计与未签到日期，表格为每天的签到情况
+func attendanceSummaryMessage(acct string, month time.Time, days []utils.AttendanceDay) notify.Message {
+	table := [][]string{{"日期", "星期", "状态", "签到时间", "签到地址", "失败次数"}}
+	var signed int
+	var missing []string
+	for _, d := range days {
+		row := []string{d.Date.Format("01-02"), weekdayNames[d.Date.Weekday()], "未签到", "", "", strconv.Itoa(d.Failures)}
+		if d.Signed {
+			signed++
+			row[2], row[3], row[4] = "已签到", d.SignedAt.Format("15:04:05"), d.Address
+		} else {
+			note := d.Date.Format("01-02")
+			if d.LastError != "" {
+				note += "(" + d.LastError + ")"
+			}
+			missing = append(missing, note)
+		}
+		table = append(table, row)
+	}
+
+	return notify.Message{Event: notify.EventMonthlySummary, Account: acct, Table: table, Data: notify.Data{
+		"year":     month.Year(),
+		"month":    int(month.Month()),
+		"days":     len(days),
+		"signed":   signed,
+		"unsigned": len(days) - signed,
+		"until":    days[len(days)-1].Date.Format("01-02"),
+		"missing":  strings.Join(missing, "、"),
+	}}
+}
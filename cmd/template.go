@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"xixunyunsign/notify"
+	"xixunyunsign/utils"
+)
+
+// TemplateDir 是自定义通知模板所在的目录，其中的 <事件>.tmpl 覆盖内置模板，目录不存在时使用内置模板。
+var TemplateDir = envOrDefault("XIXUN_TEMPLATE_DIR", "templates")
+
+// AddTemplateFlag 在根命令上注册设置通知模板目录的全局参数。
+func AddTemplateFlag(root *cobra.Command) {
+	root.PersistentFlags().StringVar(&TemplateDir, "template_dir", TemplateDir, "自定义通知模板目录(环境变量 XIXUN_TEMPLATE_DIR)")
+}
+
+// renderMessage 使用通知模板生成标题与正文，并补充账号的姓名与班级。
+// 自定义模板有误时记录日志并使用内置模板，每次发送都重新读取模板，修改后无需重启守护进程。
+func renderMessage(msg notify.Message) notify.Message {
+	data := notify.Data{}
+	for k, v := range msg.Data {
+		data[k] = v
+	}
+	if _, ok := data["user_name"]; !ok && msg.Account != "" {
+		if userName, className, err := utils.GetUserProfile(msg.Account); err == nil {
+			data["user_name"], data["class_name"] = userName, className
+		}
+	}
+	msg.Data = data
+
+	templates, err := notify.LoadTemplates(TemplateDir)
+	if err == nil {
+		var rendered notify.Message
+		if rendered, err = templates.Render(msg); err == nil {
+			return rendered
+		}
+	}
+	log.Printf("自定义通知模板有误，使用内置模板: %v\n", err)
+	rendered, err := notify.DefaultTemplates().Render(msg)
+	if err != nil {
+		log.Printf("生成通知失败: %v\n", err)
+		return msg
+	}
+	return rendered
+}
+
+var templateForce bool
+
+var notifyTemplateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "管理通知模板",
+	Long: `通知的标题与正文由 Go text/template 模板生成，每种事件一个 <事件>.tmpl 文件，
+放在 --template_dir 指定的目录(默认为 templates，环境变量 XIXUN_TEMPLATE_DIR)中即可覆盖内置模板。
+模板可用的字段见 README 的「通知模板」一节。`,
+}
+
+var notifyTemplateInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "将内置模板导出到模板目录，便于修改",
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := notify.DefaultTemplateFiles()
+		if err != nil {
+			fmt.Println("读取内置模板失败:", err)
+			return
+		}
+		if err := os.MkdirAll(TemplateDir, 0o755); err != nil {
+			fmt.Println("创建模板目录失败:", err)
+			return
+		}
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			path := filepath.Join(TemplateDir, name)
+			if _, err := os.Stat(path); err == nil && !templateForce {
+				fmt.Printf("跳过已存在的 %s\n", path)
+				continue
+			}
+			if err := os.WriteFile(path, []byte(files[name]), 0o644); err != nil {
+				fmt.Println("写入模板失败:", err)
+				return
+			}
+			fmt.Printf("已写入 %s\n", path)
+		}
+	},
+}
+
+var notifyTemplateShowCmd = &cobra.Command{
+	Use:   "show [事件...]",
+	Short: "使用示例数据预览通知模板",
+	Long:  "使用示例数据渲染模板目录中的模板(未覆盖的事件使用内置模板)，不指定事件时预览全部事件。",
+	Run: func(cmd *cobra.Command, args []string) {
+		templates, err := notify.LoadTemplates(TemplateDir)
+		if err != nil {
+			fmt.Println("读取通知模板失败:", err)
+			return
+		}
+		events := args
+		if len(events) == 0 {
+			events = templates.Events()
+		}
+		for i, event := range events {
+			sample, ok := sampleMessages()[event]
+			if !ok {
+				sample = notify.Message{Event: event}
+			}
+			sample.Account, sample.Time = "2021000001", time.Date(2026, 10, 18, 8, 0, 0, 0, time.Local)
+			msg, err := templates.Render(sample)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("== %s ==\n%s\n%s\n%s\n", event, msg.Title, strings.Repeat("-", 20), msg.Body)
+		}
+	},
+}
+
+// sampleMessages 返回各事件预览时使用的示例数据
+func sampleMessages() map[string]notify.Message {
+	profile := notify.Data{"user_name": "张三", "class_name": "软件技术2101"}
+	with := func(d notify.Data) notify.Data {
+		for k, v := range profile {
+			d[k] = v
+		}
+		return d
+	}
+	return map[string]notify.Message{
+		notify.EventSignSuccess: {Event: notify.EventSignSuccess, Data: with(notify.Data{
+			"message": "签到成功", "address": "江苏省南京市玄武区北京东路41号", "latitude": "32.05", "longitude": "118.79"})},
+		notify.EventSignFailure: {Event: notify.EventSignFailure, Data: with(notify.Data{
+			"message": "不在签到范围内", "code": 40000, "address": "江苏省南京市玄武区北京东路41号",
+			"retry_note": "已加入重试队列(ID 1)，将于 08:05:00 重试，截止 10:00。"})},
+		notify.EventTokenExpired: {Event: notify.EventTokenExpired, Data: with(notify.Data{"message": "登录状态已失效"})},
+		notify.EventReportSubmitted: {Event: notify.EventReportSubmitted, Data: with(notify.Data{
+			"report_name": "周报", "business_type": "week", "start_date": "2026-10-12", "end_date": "2026-10-18", "message": "提交成功"})},
+		notify.EventReportCommented: {Event: notify.EventReportCommented, Data: with(notify.Data{
+			"report_name": "周报", "business_type": "week", "start_date": "2026-10-12", "end_date": "2026-10-18",
+			"comment": "内容充实，继续保持。", "commenter": "李老师"})},
+		notify.EventMissedRun: {Event: notify.EventMissedRun, ScheduleID: 1, Data: with(notify.Data{
+			"missed_at": time.Date(2026, 10, 18, 7, 30, 0, 0, time.Local)})},
+		notify.EventMonthlySummary: {Event: notify.EventMonthlySummary, Data: with(notify.Data{
+			"year": 2026, "month": 9, "days": 30, "signed": 29, "unsigned": 1, "until": "09-30", "missing": "09-15(不在签到范围内)"})},
+		notify.EventTest: {Event: notify.EventTest, Data: with(notify.Data{})},
+	}
+}
+
+func init() {
+	notifyTemplateInitCmd.Flags().BoolVar(&templateForce, "force", false, "覆盖已存在的模板文件")
+	notifyTemplateCmd.AddCommand(notifyTemplateInitCmd, notifyTemplateShowCmd)
+	NotifyCmd.AddCommand(notifyTemplateCmd)
+}
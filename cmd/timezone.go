@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Timezone 是定时任务默认使用的时区，与运行环境(如 UTC 的 Docker 容器)的时区无关。
+// 可通过 --tz 参数或环境变量 XIXUN_TZ 修改。
+var Timezone = envOrDefault("XIXUN_TZ", "Asia/Shanghai")
+
+// AddTimezoneFlag 在根命令上注册设置默认时区的全局参数。
+func AddTimezoneFlag(root *cobra.Command) {
+	root.PersistentFlags().StringVar(&Timezone, "tz", Timezone, "定时任务默认时区(环境变量 XIXUN_TZ)")
+}
+
+// location 返回 Timezone 对应的时区
+func location() (*time.Location, error) {
+	loc, err := time.LoadLocation(Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("时区 %q 无效: %v", Timezone, err)
+	}
+	return loc, nil
+}
@@ -2,52 +2,34 @@ package main
 
 import (
 	"github.com/spf13/cobra"
-	"log"
+	"os"
+	_ "time/tzdata" // 内置时区数据，精简镜像中没有 /usr/share/zoneinfo 时也能加载 Asia/Shanghai
 	"xixunyunsign/cmd"
-	"xixunyunsign/utils"
 )
 
 func main() {
-	// 初始化数据库
-	err := utils.InitDB()
-	if err != nil {
-		log.Fatalf("数据库初始化失败: %v", err)
-	}
-	//
-	//// 初始化定时任务调度器
-	//_, err = utils.InitScheduler(utils.PerformSign)
-	//if err != nil {
-	//	log.Printf("初始化定时任务调度器失败: %v\n", err)
-	//}
-
-	// 设置根命令
+	// 设置根命令，数据库在解析参数后打开，以便 --stateless 生效
 	var rootCmd = &cobra.Command{Use: "xixun"}
+	cmd.AddDatabaseFlags(rootCmd)
+	cmd.AddEndpointFlags(rootCmd)
+	cmd.AddTimezoneFlag(rootCmd)
+	cmd.AddTemplateFlag(rootCmd)
 	rootCmd.AddCommand(cmd.LoginCmd)
 	rootCmd.AddCommand(cmd.QueryCmd)
 	rootCmd.AddCommand(cmd.SignCmd)
 	rootCmd.AddCommand(cmd.SchoolSearchIDCmd)
 	rootCmd.AddCommand(cmd.ExperimentalCmd)
-	//rootCmd.AddCommand(cmd.ScheduleCmd) // 添加 schedule 命令
-	//// 设置优雅关闭信号
-	//stopChan := make(chan os.Signal, 1)
-	//signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
-	//
-	//// 在单独的 goroutine 中执行命令
-	//go func() {
-	//	if err := rootCmd.Execute(); err != nil {
-	//		log.Println(err)
-	//		os.Exit(1)
-	//	}
-	//}()
-	//
-	//// 等待中断信号
-	//<-stopChan
-	//log.Println("收到停止信号，正在关闭调度器...")
-	//
-	//// 停止调度器
-	//ctx := scheduler.StopScheduler()
-	//<-ctx.Done()
-	//log.Println("调度器已停止，程序退出")
-	// 执行根命令
-	rootCmd.Execute()
+	rootCmd.AddCommand(cmd.DBCmd)
+	rootCmd.AddCommand(cmd.HistoryCmd)
+	rootCmd.AddCommand(cmd.ScheduleCmd)
+	rootCmd.AddCommand(cmd.HolidayCmd)
+	rootCmd.AddCommand(cmd.RetryCmd)
+	rootCmd.AddCommand(cmd.RunCmd)
+	rootCmd.AddCommand(cmd.NotifyCmd)
+	rootCmd.AddCommand(cmd.DaemonCmd) // 定时任务守护进程
+
+	// 执行根命令，run 等命令失败时以对应的退出码退出
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(cmd.ExitCode(err))
+	}
 }
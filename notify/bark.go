@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	Register("bark", func(cfg Config) (Notifier, error) {
+		b := &Bark{Server: cfg["server"], DeviceKey: cfg["device_key"], Group: cfg["group"], Sound: cfg["sound"], Level: cfg["level"], Icon: cfg["icon"]}
+		if b.Server == "" || b.DeviceKey == "" {
+			return nil, errors.New("bark 缺少 server 或 device_key")
+		}
+		return b, nil
+	})
+	// bark://host[/prefix]/device_key，barks:// 使用 https
+	for _, name := range []string{"bark", "barks"} {
+		secure := name == "barks"
+		RegisterScheme(name, "bark", func(u *url.URL) (Config, error) {
+			prefix, key := splitPath(u)
+			server, err := serverURL(u, secure, prefix)
+			if err != nil {
+				return nil, err
+			}
+			if key == "" {
+				return nil, errors.New("缺少 device_key，格式为 bark://host/device_key")
+			}
+			cfg := queryConfig(u)
+			cfg["server"], cfg["device_key"] = server, key
+			return cfg, nil
+		})
+	}
+}
+
+// Bark 推送到 iOS 的 Bark 应用
+type Bark struct {
+	Server    string // 如 https://api.day.app
+	DeviceKey string
+	Group     string // 消息分组
+	Sound     string // 提示音
+	Level     string // 时效性: active、timeSensitive、passive
+	Icon      string // 图标地址
+	Client    *http.Client
+}
+
+type barkRequest struct {
+	DeviceKey string `json:"device_key"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Group     string `json:"group,omitempty"`
+	Sound     string `json:"sound,omitempty"`
+	Level     string `json:"level,omitempty"`
+	Icon      string `json:"icon,omitempty"`
+}
+
+type barkResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send 推送一条消息，Bark 返回的 code 不为 200 时返回 ProviderError
+func (b *Bark) Send(ctx context.Context, msg Message) error {
+	var resp barkResponse
+	err := postJSON(ctx, b.Client, "bark", strings.TrimRight(b.Server, "/")+"/push", barkRequest{
+		DeviceKey: b.DeviceKey, Title: msg.Title, Body: msg.Body,
+		Group: b.Group, Sound: b.Sound, Level: b.Level, Icon: b.Icon,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Code != 0 && resp.Code != http.StatusOK {
+		return &ProviderError{Provider: "bark", Status: http.StatusOK, Code: resp.Code, Message: resp.Message}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("gotify", func(cfg Config) (Notifier, error) {
+		g := &Gotify{Server: cfg["server"], Token: cfg["token"], Priority: 5}
+		if g.Server == "" || g.Token == "" {
+			return nil, errors.New("gotify 缺少 server 或 token")
+		}
+		if p := cfg["priority"]; p != "" {
+			priority, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, errors.New("gotify 的 priority 应为整数")
+			}
+			g.Priority = priority
+		}
+		return g, nil
+	})
+	// gotify://host[/prefix]/token，gotifys:// 使用 https
+	for _, name := range []string{"gotify", "gotifys"} {
+		secure := name == "gotifys"
+		RegisterScheme(name, "gotify", func(u *url.URL) (Config, error) {
+			prefix, token := splitPath(u)
+			server, err := serverURL(u, secure, prefix)
+			if err != nil {
+				return nil, err
+			}
+			if token == "" {
+				return nil, errors.New("缺少应用 token，格式为 gotify://host/token")
+			}
+			cfg := queryConfig(u)
+			cfg["server"], cfg["token"] = server, token
+			return cfg, nil
+		})
+	}
+}
+
+// Gotify 推送到自建的 Gotify 服务
+type Gotify struct {
+	Server   string
+	Token    string // 应用 token
+	Priority int
+	Client   *http.Client
+}
+
+type gotifyRequest struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+// Send 推送一条 Markdown 格式的消息
+func (g *Gotify) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, g.Client, "gotify", strings.TrimRight(g.Server, "/")+"/message?token="+url.QueryEscape(g.Token), gotifyRequest{
+		Title:    msg.Title,
+		Message:  msg.Body,
+		Priority: g.Priority,
+		Extras:   map[string]any{"client::display": map[string]string{"contentType": "text/markdown"}},
+	}, nil)
+}
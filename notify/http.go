@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// postJSON 以 JSON 格式 POST body，HTTP 状态异常时返回 ProviderError，out 不为 nil 时解析响应
+func postJSON(ctx context.Context, client *http.Client, provider, url string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s 地址有误: %w", provider, stripURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json;charset=utf-8")
+	return doRequest(client, provider, req, out)
+}
+
+// doRequest 发送请求，HTTP 状态异常时返回 ProviderError，out 不为 nil 时将响应解析为 JSON
+func doRequest(client *http.Client, provider string, req *http.Request, out any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送到 %s 失败: %w", provider, stripURL(err))
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("读取 %s 响应失败: %w", provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &ProviderError{Provider: provider, Status: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+	}
+	if out == nil || len(bytes.TrimSpace(respBody)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", provider, err)
+	}
+	return nil
+}
+
+// stripURL 去掉 *url.Error 中的请求地址。Server酱的 SendKey、Bark 的设备 key 等密钥就在地址中，
+// 错误信息会写入日志，因此只保留底层错误。
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"strings"
+	"time"
+)
+
+// now 返回当前时间，测试中替换为固定时间以校验签名
+var now = time.Now
+
+// failed 判断事件是否表示失败，用于选择卡片颜色等
+func failed(event string) bool {
+	switch event {
+	case EventSignFailure, EventTokenExpired, EventMissedRun:
+		return true
+	}
+	return false
+}
+
+// markdownText 将通知排版为 Markdown：标题、正文，以及账号与时间。
+// lineBreak 为正文中换行使用的分隔符，钉钉的 Markdown 需要空行才会换行。
+func markdownText(msg Message, lineBreak string) string {
+	return "### " + msg.Title + "\n\n" + markdownBody(msg, lineBreak)
+}
+
+// markdownBody 将通知正文排版为 Markdown，末尾附上账号与时间
+func markdownBody(msg Message, lineBreak string) string {
+	var b strings.Builder
+	lines := strings.Split(strings.TrimSpace(msg.Body), "\n")
+	b.WriteString(strings.Join(lines, lineBreak))
+	b.WriteString("\n\n> ")
+	if msg.Account != "" {
+		b.WriteString("账号 " + msg.Account + " · ")
+	}
+	t := msg.Time
+	if t.IsZero() {
+		t = now()
+	}
+	b.WriteString(t.Format("2006-01-02 15:04:05"))
+	return b.String()
+}
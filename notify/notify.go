@@ -0,0 +1,158 @@
+// Package notify 定义通知渠道(Notifier)及其注册表。签到、报告与定时任务的通知
+// 都构造成 Message，由 Dispatcher 同时发送到账号配置的所有渠道。
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 通知事件类型
+const (
+	EventSignSuccess     = "sign_success"     // 签到成功
+	EventSignFailure     = "sign_failure"     // 签到失败
+	EventTokenExpired    = "token_expired"    // 登录状态失效且无法自动重新登录
+	EventReportSubmitted = "report_submitted" // 报告已提交
+	EventReportCommented = "report_commented" // 报告收到老师批阅
+	EventMissedRun       = "missed_run"       // 守护进程停机期间错过了定时任务
+	EventMonthlySummary  = "monthly_summary"  // 月度考勤汇总
+	EventTest            = "test"             // notify test 发送的测试消息
+)
+
+// Message 是一条通知
+type Message struct {
+	Event      string
+	Account    string
+	ScheduleID int // 触发通知的定时任务，不是定时任务时为 0
+	Title      string
+	Body       string // 正文，支持 Markdown 的渠道按 Markdown 展示
+	// Table 为可选的表格，第一行为表头。邮件以 HTML 表格展示，其他渠道只发送正文，
+	// 因此正文中应包含表格的文本形式。
+	Table [][]string
+	Time  time.Time
+	// Data 为通知模板的数据，Templates.Render 根据 Event 对应的模板与 Data 生成 Title 与 Body
+	Data Data
+}
+
+// Notifier 将通知发送到一个渠道
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config 是创建通知渠道的参数，如 Server酱的 sendkey
+type Config map[string]string
+
+// Factory 根据参数创建通知渠道
+type Factory func(cfg Config) (Notifier, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register 注册一种通知渠道，kind 重复时 panic
+func Register(kind string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[kind]; dup {
+		panic("notify: 重复注册通知渠道 " + kind)
+	}
+	registry[kind] = factory
+}
+
+// New 使用已注册的 kind 创建通知渠道
+func New(kind string, cfg Config) (Notifier, error) {
+	registryMu.RLock()
+	factory, ok := registry[kind]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的通知渠道: %s(支持 %v)", kind, Kinds())
+	}
+	if cfg == nil {
+		cfg = Config{}
+	}
+	return factory(cfg)
+}
+
+// Kinds 返回所有已注册的通知渠道
+func Kinds() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	kinds := make([]string, 0, len(registry))
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// ProviderError 表示通知服务拒绝了请求(HTTP 状态异常或返回了错误码)
+type ProviderError struct {
+	Provider string
+	Status   int // HTTP 状态码
+	Code     int // 服务返回的错误码
+	Message  string
+}
+
+func (e *ProviderError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("%s 返回错误(code=%d): %s", e.Provider, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s HTTP 状态异常: %d %s", e.Provider, e.Status, e.Message)
+}
+
+// DefaultTimeout 是发送到单个渠道的超时时间
+const DefaultTimeout = 15 * time.Second
+
+type channel struct {
+	name     string
+	notifier Notifier
+}
+
+// Dispatcher 将通知同时发送到多个渠道，单个渠道失败不影响其他渠道
+type Dispatcher struct {
+	channels []channel
+	// Timeout 为发送到单个渠道的超时时间，为 0 时使用 DefaultTimeout
+	Timeout time.Duration
+}
+
+// Add 添加一个渠道，name 用于错误信息
+func (d *Dispatcher) Add(name string, n Notifier) {
+	d.channels = append(d.channels, channel{name, n})
+}
+
+// Len 返回渠道数量
+func (d *Dispatcher) Len() int {
+	return len(d.channels)
+}
+
+// Send 并发发送到所有渠道，返回各渠道的错误(使用 errors.Join 合并)
+func (d *Dispatcher) Send(ctx context.Context, msg Message) error {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	errs := make([]error, len(d.channels))
+	var wg sync.WaitGroup
+	for i, c := range d.channels {
+		wg.Add(1)
+		go func(i int, c channel) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			if err := c.notifier.Send(ctx, msg); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", c.name, err)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerChan(t *testing.T) {
+	var got serverChanRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SCTok.send":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+			w.Write([]byte(`{"code":0,"message":"","data":{"pushid":"1","readkey":"k","error":"SUCCESS"}}`))
+		case "/SCTbad.send":
+			w.Write([]byte(`{"code":40001,"message":"bad pushkey","data":null}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	n, err := New("serverchan", Config{"sendkey": "SCTok", "base_url": ts.URL})
+	require.NoError(t, err)
+	require.NoError(t, n.Send(context.Background(), Message{Title: "签到成功", Body: "ok"}))
+	assert.Equal(t, serverChanRequest{Title: "签到成功", Desp: "ok", Channel: "9"}, got)
+
+	// 服务返回错误码或 HTTP 状态异常时返回 ProviderError
+	var perr *ProviderError
+	err = (&ServerChan{SendKey: "SCTbad", BaseURL: ts.URL}).Send(context.Background(), Message{Title: "t"})
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, 40001, perr.Code)
+	assert.Equal(t, "bad pushkey", perr.Message)
+
+	err = (&ServerChan{SendKey: "SCTdown", BaseURL: ts.URL}).Send(context.Background(), Message{Title: "t"})
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, http.StatusInternalServerError, perr.Status)
+
+	_, err = New("serverchan", nil)
+	assert.Error(t, err, "缺少 sendkey")
+	_, err = New("unknown", nil)
+	assert.Error(t, err)
+}
+
+type notifierFunc func(ctx context.Context, msg Message) error
+
+func (f notifierFunc) Send(ctx context.Context, msg Message) error { return f(ctx, msg) }
+
+func TestDispatcher(t *testing.T) {
+	sent := make(chan string, 2)
+	var d Dispatcher
+	d.Add("a", notifierFunc(func(ctx context.Context, msg Message) error {
+		sent <- "a:" + msg.Title
+		return nil
+	}))
+	d.Add("b", notifierFunc(func(ctx context.Context, msg Message) error {
+		return errors.New("boom")
+	}))
+	d.Add("c", notifierFunc(func(ctx context.Context, msg Message) error {
+		assert.False(t, msg.Time.IsZero())
+		sent <- "c:" + msg.Title
+		return nil
+	}))
+
+	// 单个渠道失败不影响其他渠道
+	err := d.Send(context.Background(), Message{Title: "hi"})
+	require.Error(t, err)
+	assert.Equal(t, "b: boom", err.Error())
+	close(sent)
+	var got []string
+	for s := range sent {
+		got = append(got, s)
+	}
+	assert.ElementsMatch(t, []string{"a:hi", "c:hi"}, got)
+	assert.Contains(t, Kinds(), "serverchan")
+}
+
+func TestSendErrorHidesSecrets(t *testing.T) {
+	// 连接失败时 *url.Error 包含完整的请求地址，其中有 SendKey 与设备 key
+	ts := httptest.NewServer(http.NotFoundHandler())
+	base := ts.URL
+	ts.Close()
+
+	for _, n := range []Notifier{
+		&ServerChan{SendKey: "SCTsecretkey", BaseURL: base},
+		&Bark{Server: base, DeviceKey: "secretkey"},
+	} {
+		err := n.Send(context.Background(), Message{Title: "t"})
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "secretkey")
+	}
+
+	err := (&ServerChan{SendKey: "SCTsecretkey", BaseURL: "http://[::1"}).Send(context.Background(), Message{Title: "t"})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secretkey")
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultNtfyServer 是 ntfy 官方服务地址
+const DefaultNtfyServer = "https://ntfy.sh"
+
+func init() {
+	Register("ntfy", func(cfg Config) (Notifier, error) {
+		n := &Ntfy{Server: cfg["server"], Topic: cfg["topic"], Token: cfg["token"], Username: cfg["username"],
+			Password: cfg["password"], Priority: cfg["priority"], Tags: cfg["tags"]}
+		if n.Topic == "" {
+			return nil, errors.New("ntfy 缺少 topic")
+		}
+		return n, nil
+	})
+	// ntfy://topic 使用 ntfy.sh，ntfy://[user:pass@]host[/prefix]/topic 使用自建服务，ntfys:// 使用 https
+	for _, name := range []string{"ntfy", "ntfys"} {
+		secure := name == "ntfys"
+		RegisterScheme(name, "ntfy", func(u *url.URL) (Config, error) {
+			cfg := queryConfig(u)
+			prefix, topic := splitPath(u)
+			if topic == "" {
+				cfg["server"], cfg["topic"] = DefaultNtfyServer, u.Host
+			} else {
+				server, err := serverURL(u, secure, prefix)
+				if err != nil {
+					return nil, err
+				}
+				cfg["server"], cfg["topic"] = server, topic
+			}
+			if cfg["topic"] == "" {
+				return nil, errors.New("缺少 topic，格式为 ntfy://host/topic")
+			}
+			if u.User != nil {
+				cfg["username"] = u.User.Username()
+				cfg["password"], _ = u.User.Password()
+			}
+			return cfg, nil
+		})
+	}
+}
+
+// Ntfy 发布消息到 ntfy 主题
+type Ntfy struct {
+	Server   string // 为空时使用 DefaultNtfyServer
+	Topic    string
+	Token    string // 访问令牌，与用户名密码二选一
+	Username string
+	Password string
+	Priority string // 1-5 或 min、low、default、high、max
+	Tags     string // 以逗号分隔的标签或 emoji 短代码
+	Client   *http.Client
+}
+
+// Send 发布一条 Markdown 格式的消息
+func (n *Ntfy) Send(ctx context.Context, msg Message) error {
+	server := n.Server
+	if server == "" {
+		server = DefaultNtfyServer
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(server, "/")+"/"+url.PathEscape(n.Topic), strings.NewReader(msg.Body))
+	if err != nil {
+		return fmt.Errorf("ntfy 地址有误: %w", stripURL(err))
+	}
+	// 请求头只能包含 ASCII，标题使用 RFC 2047 编码
+	req.Header.Set("Title", mimeWord(msg.Title))
+	req.Header.Set("Markdown", "yes")
+	if n.Priority != "" {
+		req.Header.Set("Priority", n.Priority)
+	}
+	if n.Tags != "" {
+		req.Header.Set("Tags", n.Tags)
+	}
+	switch {
+	case n.Token != "":
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	case n.Username != "":
+		req.SetBasicAuth(n.Username, n.Password)
+	}
+	return doRequest(n.Client, "ntfy", req, nil)
+}
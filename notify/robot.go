@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 群机器人的默认接口地址
+const (
+	DefaultDingTalkBaseURL = "https://oapi.dingtalk.com"
+	DefaultWeComBaseURL    = "https://qyapi.weixin.qq.com"
+	DefaultFeishuBaseURL   = "https://open.feishu.cn"
+)
+
+func init() {
+	Register("dingtalk", func(cfg Config) (Notifier, error) {
+		d := &DingTalk{AccessToken: cfg["access_token"], Secret: cfg["secret"], BaseURL: cfg["base_url"]}
+		if d.AccessToken == "" {
+			return nil, errors.New("dingtalk 缺少 access_token")
+		}
+		return d, nil
+	})
+	// dingtalk://ACCESS_TOKEN[?secret=SEC...]
+	RegisterScheme("dingtalk", "dingtalk", func(u *url.URL) (Config, error) {
+		cfg := queryConfig(u)
+		cfg["access_token"] = u.Host
+		return cfg, nil
+	})
+
+	Register("wecom", func(cfg Config) (Notifier, error) {
+		w := &WeCom{Key: cfg["key"], BaseURL: cfg["base_url"]}
+		if w.Key == "" {
+			return nil, errors.New("wecom 缺少 key")
+		}
+		return w, nil
+	})
+	// wecom://KEY
+	RegisterScheme("wecom", "wecom", func(u *url.URL) (Config, error) {
+		cfg := queryConfig(u)
+		cfg["key"] = u.Host
+		return cfg, nil
+	})
+
+	Register("feishu", func(cfg Config) (Notifier, error) {
+		f := &Feishu{Token: cfg["token"], Secret: cfg["secret"], BaseURL: cfg["base_url"]}
+		if f.Token == "" {
+			return nil, errors.New("feishu 缺少 token")
+		}
+		return f, nil
+	})
+	// feishu://TOKEN[?secret=...]，TOKEN 为机器人 webhook 地址最后一段
+	RegisterScheme("feishu", "feishu", func(u *url.URL) (Config, error) {
+		cfg := queryConfig(u)
+		cfg["token"] = u.Host
+		return cfg, nil
+	})
+}
+
+// hmacBase64 返回 base64 编码的 HMAC-SHA256
+func hmacBase64(key, message string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// robotResponse 是钉钉与企业微信机器人的响应
+type robotResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (r robotResponse) err(provider string) error {
+	if r.ErrCode != 0 {
+		return &ProviderError{Provider: provider, Status: http.StatusOK, Code: r.ErrCode, Message: r.ErrMsg}
+	}
+	return nil
+}
+
+// DingTalk 发送到钉钉群的自定义机器人
+type DingTalk struct {
+	AccessToken string
+	Secret      string // 加签密钥(SEC 开头)，为空时不签名
+	BaseURL     string // 为空时使用 DefaultDingTalkBaseURL
+	Client      *http.Client
+}
+
+// dingTalkSign 返回钉钉加签的签名：以 secret 为密钥对 "毫秒时间戳\nsecret" 做 HMAC-SHA256，再进行 base64 编码
+func dingTalkSign(timestamp int64, secret string) string {
+	return hmacBase64(secret, strconv.FormatInt(timestamp, 10)+"\n"+secret)
+}
+
+// Send 发送 Markdown 消息
+func (d *DingTalk) Send(ctx context.Context, msg Message) error {
+	base := d.BaseURL
+	if base == "" {
+		base = DefaultDingTalkBaseURL
+	}
+	query := url.Values{"access_token": {d.AccessToken}}
+	if d.Secret != "" {
+		ts := now().UnixMilli()
+		query.Set("timestamp", strconv.FormatInt(ts, 10))
+		query.Set("sign", dingTalkSign(ts, d.Secret))
+	}
+	body := map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": msg.Title, "text": markdownText(msg, "\n\n")},
+	}
+	var resp robotResponse
+	if err := postJSON(ctx, d.Client, "dingtalk", strings.TrimRight(base, "/")+"/robot/send?"+query.Encode(), body, &resp); err != nil {
+		return err
+	}
+	return resp.err("dingtalk")
+}
+
+// WeCom 发送到企业微信群机器人。企业微信群机器人不支持签名校验，webhook 地址中的 key 即为凭据，请妥善保管。
+type WeCom struct {
+	Key     string
+	BaseURL string // 为空时使用 DefaultWeComBaseURL
+	Client  *http.Client
+}
+
+// Send 发送 Markdown 消息，成功使用绿色标题，失败使用红色标题
+func (w *WeCom) Send(ctx context.Context, msg Message) error {
+	base := w.BaseURL
+	if base == "" {
+		base = DefaultWeComBaseURL
+	}
+	color := "info"
+	if failed(msg.Event) {
+		color = "warning"
+	}
+	content := `### <font color="` + color + `">` + msg.Title + "</font>\n\n" + markdownBody(msg, "\n")
+	body := map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": content},
+	}
+	var resp robotResponse
+	if err := postJSON(ctx, w.Client, "wecom", strings.TrimRight(base, "/")+"/cgi-bin/webhook/send?key="+url.QueryEscape(w.Key), body, &resp); err != nil {
+		return err
+	}
+	return resp.err("wecom")
+}
+
+// Feishu 发送到飞书群的自定义机器人
+type Feishu struct {
+	Token   string
+	Secret  string // 签名校验密钥，为空时不签名
+	BaseURL string // 为空时使用 DefaultFeishuBaseURL，使用 Lark 时为 https://open.larksuite.com
+	Client  *http.Client
+}
+
+// feishuSign 返回飞书签名校验的签名：以 "秒级时间戳\nsecret" 为密钥对空字符串做 HMAC-SHA256，再进行 base64 编码
+func feishuSign(timestamp int64, secret string) string {
+	return hmacBase64(strconv.FormatInt(timestamp, 10)+"\n"+secret, "")
+}
+
+type feishuResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// Send 发送消息卡片，成功使用绿色标题栏，失败使用红色标题栏
+func (f *Feishu) Send(ctx context.Context, msg Message) error {
+	base := f.BaseURL
+	if base == "" {
+		base = DefaultFeishuBaseURL
+	}
+	template := "green"
+	if failed(msg.Event) {
+		template = "red"
+	}
+	body := map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"header": map[string]any{
+				"title":    map[string]string{"tag": "plain_text", "content": msg.Title},
+				"template": template,
+			},
+			"elements": []any{
+				map[string]string{"tag": "markdown", "content": markdownBody(msg, "\n")},
+			},
+		},
+	}
+	if f.Secret != "" {
+		ts := now().Unix()
+		body["timestamp"] = strconv.FormatInt(ts, 10)
+		body["sign"] = feishuSign(ts, f.Secret)
+	}
+	var resp feishuResponse
+	if err := postJSON(ctx, f.Client, "feishu", strings.TrimRight(base, "/")+"/open-apis/bot/v2/hook/"+url.PathEscape(f.Token), body, &resp); err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		return &ProviderError{Provider: "feishu", Status: http.StatusOK, Code: resp.Code, Message: resp.Msg}
+	}
+	return nil
+}
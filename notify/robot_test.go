@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRobotNotifiers(t *testing.T) {
+	fixed := time.Unix(1700000000, 0)
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = time.Now })
+
+	type request struct {
+		path  string
+		query map[string]string
+		body  map[string]any
+	}
+	var got []request
+	fail := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{path: r.URL.Path, query: map[string]string{}}
+		for k, v := range r.URL.Query() {
+			req.query[k] = v[0]
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req.body))
+		got = append(got, req)
+		switch {
+		case fail && r.URL.Path == "/open-apis/bot/v2/hook/fstoken":
+			w.Write([]byte(`{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`))
+		case fail:
+			w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+		case r.URL.Path == "/open-apis/bot/v2/hook/fstoken":
+			w.Write([]byte(`{"code":0,"msg":"success"}`))
+		default:
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	}))
+	defer ts.Close()
+
+	msg := Message{Event: EventSignFailure, Account: "user", Title: "签到失败", Body: "错误详细信息：不在签到范围内\n已加入重试队列", Time: fixed}
+	notifiers := []string{
+		"dingtalk://dttoken?secret=SECtest&base_url=" + ts.URL,
+		"wecom://wckey?base_url=" + ts.URL,
+		"feishu://fstoken?secret=fssecret&base_url=" + ts.URL,
+	}
+	for _, raw := range notifiers {
+		n, err := NewFromURL(raw)
+		require.NoError(t, err)
+		require.NoError(t, n.Send(context.Background(), msg), raw)
+	}
+	require.Len(t, got, 3)
+	footer := "\n\n> 账号 user · " + fixed.Format("2006-01-02 15:04:05")
+
+	// 钉钉：毫秒时间戳与签名作为查询参数，正文换行使用空行
+	assert.Equal(t, "/robot/send", got[0].path)
+	assert.Equal(t, map[string]string{"access_token": "dttoken", "timestamp": "1700000000000",
+		"sign": "aZLLrriXgn05YbwaGR7knYsLeJADjr9NwLaNNKpxh4g="}, got[0].query)
+	assert.Equal(t, map[string]any{"msgtype": "markdown", "markdown": map[string]any{"title": "签到失败",
+		"text": "### 签到失败\n\n错误详细信息：不在签到范围内\n\n已加入重试队列" + footer}}, got[0].body)
+
+	// 企业微信：不签名，失败使用 warning 颜色
+	assert.Equal(t, "/cgi-bin/webhook/send", got[1].path)
+	assert.Equal(t, map[string]string{"key": "wckey"}, got[1].query)
+	assert.Equal(t, map[string]any{"msgtype": "markdown", "markdown": map[string]any{
+		"content": "### <font color=\"warning\">签到失败</font>\n\n错误详细信息：不在签到范围内\n已加入重试队列" + footer}}, got[1].body)
+
+	// 飞书：秒级时间戳与签名放在请求体中，发送红色标题的消息卡片
+	assert.Equal(t, "/open-apis/bot/v2/hook/fstoken", got[2].path)
+	assert.Equal(t, "1700000000", got[2].body["timestamp"])
+	assert.Equal(t, "pM16hFM8LcPO4uN2mSwtlv6jeeFZFhmFo+eYVc1mu94=", got[2].body["sign"])
+	assert.Equal(t, "interactive", got[2].body["msg_type"])
+	card := got[2].body["card"].(map[string]any)
+	assert.Equal(t, "red", card["header"].(map[string]any)["template"])
+	assert.Equal(t, "签到失败", card["header"].(map[string]any)["title"].(map[string]any)["content"])
+	assert.Equal(t, []any{map[string]any{"tag": "markdown", "content": "错误详细信息：不在签到范围内\n已加入重试队列" + footer}}, card["elements"])
+
+	// 机器人返回错误码时返回 ProviderError
+	fail = true
+	for i, code := range []int{310000, 310000, 19021} {
+		n, err := NewFromURL(notifiers[i])
+		require.NoError(t, err)
+		var perr *ProviderError
+		require.ErrorAs(t, n.Send(context.Background(), msg), &perr)
+		assert.Equal(t, code, perr.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultServerChanBaseURL 是 Server酱 Turbo 版的接口地址
+const DefaultServerChanBaseURL = "https://sctapi.ftqq.com"
+
+func init() {
+	Register("serverchan", func(cfg Config) (Notifier, error) {
+		s := &ServerChan{SendKey: cfg["sendkey"], Channel: cfg["channel"], BaseURL: cfg["base_url"]}
+		if s.SendKey == "" {
+			return nil, errors.New("serverchan 缺少 sendkey")
+		}
+		return s, nil
+	})
+	// serverchan://SENDKEY[?channel=9]
+	RegisterScheme("serverchan", "serverchan", func(u *url.URL) (Config, error) {
+		cfg := queryConfig(u)
+		cfg["sendkey"] = u.Host
+		return cfg, nil
+	})
+}
+
+// ServerChan 通过 Server酱 推送到微信
+type ServerChan struct {
+	SendKey string
+	Channel string // 消息通道，为空时使用 9(方糖服务号)
+	BaseURL string // 为空时使用 DefaultServerChanBaseURL
+	Client  *http.Client
+}
+
+type serverChanRequest struct {
+	Title   string `json:"title"`             // 消息标题，必填
+	Desp    string `json:"desp,omitempty"`    // 消息内容，支持 Markdown
+	Channel string `json:"channel,omitempty"` // 动态指定消息通道
+}
+
+type serverChanResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		PushID  string `json:"pushid"`
+		ReadKey string `json:"readkey"`
+		Error   string `json:"error"`
+	} `json:"data"`
+}
+
+// Send 推送一条消息，Server酱返回的 code 不为 0 时返回 ProviderError
+func (s *ServerChan) Send(ctx context.Context, msg Message) error {
+	base := s.BaseURL
+	if base == "" {
+		base = DefaultServerChanBaseURL
+	}
+	channel := s.Channel
+	if channel == "" {
+		channel = "9"
+	}
+	var resp serverChanResponse
+	err := postJSON(ctx, s.Client, "serverchan", fmt.Sprintf("%s/%s.send", strings.TrimRight(base, "/"), s.SendKey),
+		serverChanRequest{Title: msg.Title, Desp: msg.Body, Channel: channel}, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		message := resp.Message
+		if resp.Data.Error != "" && resp.Data.Error != "SUCCESS" {
+			message += " " + resp.Data.Error
+		}
+		return &ProviderError{Provider: "serverchan", Status: http.StatusOK, Code: resp.Code, Message: message}
+	}
+	return nil
+}
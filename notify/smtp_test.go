@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert 生成 127.0.0.1 的自签名证书
+func testCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// smtpStub 是一个只处理一封邮件的 SMTP 服务器
+type smtpStub struct {
+	addr     string
+	startTLS bool   // 是否支持 STARTTLS
+	reject   string // 拒绝的收件人
+
+	tls   bool
+	auth  string
+	from  string
+	rcpts []string
+	data  string
+	done  chan struct{}
+}
+
+func newSMTPStub(t *testing.T, cert tls.Certificate, implicitTLS, startTLS bool, reject string) *smtpStub {
+	t.Helper()
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	var ln net.Listener
+	var err error
+	if implicitTLS {
+		ln, err = tls.Listen("tcp", "127.0.0.1:0", cfg)
+	} else {
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpStub{addr: ln.Addr().String(), startTLS: startTLS, reject: reject, tls: implicitTLS, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(conn, cfg)
+	}()
+	return s
+}
+
+func (s *smtpStub) serve(conn net.Conn, cfg *tls.Config) {
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	reply := func(line string) {
+		w.WriteString(line + "\r\n")
+		w.Flush()
+	}
+	reply("220 stub ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO":
+			if s.startTLS && !s.tls {
+				reply("250-stub")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-stub")
+			}
+			reply("250 AUTH PLAIN")
+		case cmd == "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, cfg)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, s.tls = tlsConn, true
+			r, w = bufio.NewReader(conn), bufio.NewWriter(conn)
+		case cmd == "AUTH":
+			s.auth = strings.TrimPrefix(line, "AUTH PLAIN ")
+			reply("235 ok")
+		case cmd == "MAIL":
+			s.from = line
+			reply("250 ok")
+		case cmd == "RCPT":
+			if s.reject != "" && strings.Contains(line, s.reject) {
+				reply("550 no such user")
+				continue
+			}
+			s.rcpts = append(s.rcpts, line)
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data = b.String()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func newTestSMTP(t *testing.T, raw string, pool *x509.CertPool) *SMTP {
+	t.Helper()
+	n, err := NewFromURL(raw)
+	require.NoError(t, err)
+	s := n.(*SMTP)
+	s.TLSConfig = &tls.Config{RootCAs: pool}
+	return s
+}
+
+func TestSMTP(t *testing.T) {
+	cert, pool := testCert(t)
+	msg := Message{
+		Event:   EventMonthlySummary,
+		Account: "user",
+		Title:   "2026年10月考勤汇总",
+		Body:    "应签到 2 天，已签到 1 天\n10-02 未签到：不在签到范围内",
+		Table:   [][]string{{"日期", "状态"}, {"10-01", "已签到"}, {"10-02", "<未签到>"}},
+		Time:    time.Date(2026, 10, 3, 9, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+	}
+
+	// STARTTLS、认证、多个收件人
+	stub := newSMTPStub(t, cert, false, true, "")
+	s := newTestSMTP(t, "smtp://bot%40example.com:pa55@"+stub.addr+"/?to=a@example.com,b@example.com", pool)
+	require.NoError(t, s.Send(context.Background(), msg))
+	<-stub.done
+	assert.True(t, stub.tls)
+	auth, err := base64.StdEncoding.DecodeString(stub.auth)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00bot@example.com\x00pa55", string(auth))
+	assert.Equal(t, "MAIL FROM:<bot@example.com>", strings.SplitN(stub.from, " BODY", 2)[0])
+	assert.Equal(t, []string{"RCPT TO:<a@example.com>", "RCPT TO:<b@example.com>"}, stub.rcpts)
+
+	m, err := mail.ReadMessage(strings.NewReader(stub.data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Title, subject)
+	assert.Equal(t, "a@example.com, b@example.com", m.Header.Get("To"))
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	text, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=UTF-8", text.Header.Get("Content-Type"))
+	plain, _ := io.ReadAll(text)
+	assert.Contains(t, string(plain), "应签到 2 天，已签到 1 天\r\n10-02 未签到：不在签到范围内")
+	assert.Contains(t, string(plain), "账号 user · 2026-10-03 09:00:00")
+	html, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=UTF-8", html.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(html)
+	assert.Contains(t, string(body), "<p style=\"margin:0 0 8px;\">应签到 2 天，已签到 1 天</p>")
+	assert.Contains(t, string(body), ">日期</th>")
+	assert.Contains(t, string(body), ">&lt;未签到&gt;</td>", "表格内容需要转义")
+	_, err = mr.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	// 带显示名称的地址：信封中只使用地址，显示名称只出现在邮件头中
+	stub = newSMTPStub(t, cert, false, true, "")
+	s = newTestSMTP(t, "smtp://bot%40example.com:pa55@"+stub.addr+"/?from="+url.QueryEscape("xixun <bot@example.com>")+
+		"&to="+url.QueryEscape("张老师 <a@example.com>"), pool)
+	require.NoError(t, s.Send(context.Background(), msg))
+	<-stub.done
+	assert.Equal(t, "MAIL FROM:<bot@example.com>", strings.SplitN(stub.from, " BODY", 2)[0])
+	assert.Equal(t, []string{"RCPT TO:<a@example.com>"}, stub.rcpts)
+	m, err = mail.ReadMessage(strings.NewReader(stub.data))
+	require.NoError(t, err)
+	fromHeader, err := m.Header.AddressList("From")
+	require.NoError(t, err)
+	assert.Equal(t, []*mail.Address{{Name: "xixun", Address: "bot@example.com"}}, fromHeader)
+	toHeader, err := m.Header.AddressList("To")
+	require.NoError(t, err)
+	assert.Equal(t, []*mail.Address{{Name: "张老师", Address: "a@example.com"}}, toHeader)
+
+	// smtps:// 直接使用 TLS，未指定发件人时使用用户名
+	stub = newSMTPStub(t, cert, true, false, "")
+	s = newTestSMTP(t, "smtps://bot%40example.com:pa55@"+stub.addr, pool)
+	require.NoError(t, s.Send(context.Background(), Message{Title: "签到成功", Body: "ok"}))
+	<-stub.done
+	assert.Equal(t, []string{"RCPT TO:<bot@example.com>"}, stub.rcpts)
+
+	// 服务器不支持 STARTTLS 时不以明文发送密码
+	stub = newSMTPStub(t, cert, false, false, "")
+	s = newTestSMTP(t, "smtp://bot%40example.com:pa55@"+stub.addr+"/?to=a@example.com", pool)
+	assert.ErrorContains(t, s.Send(context.Background(), msg), "STARTTLS")
+	<-stub.done
+	assert.Empty(t, stub.auth)
+
+	// 服务器拒绝收件人时返回 ProviderError
+	stub = newSMTPStub(t, cert, false, true, "b@example.com")
+	s = newTestSMTP(t, "smtp://bot%40example.com:pa55@"+stub.addr+"/?to=a@example.com,b@example.com", pool)
+	var perr *ProviderError
+	require.ErrorAs(t, s.Send(context.Background(), msg), &perr)
+	assert.Equal(t, 550, perr.Code)
+}
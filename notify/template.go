@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Data 是通知模板的数据，模板中以 {{.account}} 的形式引用。所有事件都包含以下字段：
+//
+//	event       事件类型，如 sign_success
+//	account     账号
+//	user_name   姓名(未登录过时为空)
+//	class_name  班级(未登录过时为空)
+//	address     签到地址(非签到事件为空)
+//	code        习讯云接口返回的 code(没有时为 0)
+//	message     习讯云接口返回的消息或错误信息
+//	timestamp   事件发生的时间(time.Time)
+//	schedule_id 触发通知的定时任务 ID(不是定时任务时为 0)
+//
+// 各事件的其他字段见 README。
+type Data map[string]any
+
+// commonKeys 是所有事件都包含的字段及其零值，保证模板中引用时不会输出 <no value>
+var commonKeys = Data{
+	"event": "", "account": "", "user_name": "", "class_name": "", "address": "",
+	"code": 0, "message": "", "schedule_id": 0,
+}
+
+// eventKeys 是各事件特有的字段及其零值
+var eventKeys = map[string]Data{
+	EventSignSuccess:     {"retry": false, "attempts": 0, "latitude": "", "longitude": ""},
+	EventSignFailure:     {"retry": false, "attempts": 0, "reason": "", "retry_note": ""},
+	EventReportSubmitted: {"report_name": "报告", "business_type": "", "start_date": "", "end_date": ""},
+	EventReportCommented: {"report_name": "报告", "business_type": "", "start_date": "", "end_date": "",
+		"comment": "", "commenter": ""},
+	EventMissedRun:      {"missed_at": time.Time{}},
+	EventMonthlySummary: {"year": 0, "month": 0, "days": 0, "signed": 0, "unsigned": 0, "until": "", "missing": ""},
+}
+
+//go:embed templates/*.tmpl
+var defaultFS embed.FS
+
+// templateFuncs 是模板中可用的函数
+var templateFuncs = template.FuncMap{
+	// datetime 将时间格式化为 2006-01-02 15:04:05
+	"datetime": func(v any) string { return formatTime(v, "2006-01-02 15:04:05") },
+	// date 将时间格式化为 2006-01-02
+	"date": func(v any) string { return formatTime(v, "2006-01-02") },
+}
+
+func formatTime(v any, layout string) string {
+	if t, ok := v.(time.Time); ok && !t.IsZero() {
+		return t.Format(layout)
+	}
+	return ""
+}
+
+// eventTemplate 是一个事件的标题与正文模板
+type eventTemplate struct {
+	title, body *template.Template
+}
+
+// Templates 是各事件的通知模板
+type Templates struct {
+	events map[string]eventTemplate
+}
+
+// DefaultTemplates 返回内置的通知模板
+func DefaultTemplates() *Templates {
+	t, err := loadTemplates(nil)
+	if err != nil {
+		panic("notify: 内置模板有误: " + err.Error())
+	}
+	return t
+}
+
+// LoadTemplates 读取 dir 中的 <事件>.tmpl 覆盖内置模板，dir 为空或不存在时只使用内置模板。
+// 文件中可以用 {{define "title"}} 与 {{define "body"}} 分别定义标题与正文，只定义其中一个时另一个使用内置模板；
+// 没有 define 时整个文件作为正文。common.tmpl 中的定义(如 "who")对所有事件生效。
+func LoadTemplates(dir string) (*Templates, error) {
+	if dir == "" {
+		return DefaultTemplates(), nil
+	}
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return DefaultTemplates(), nil
+	}
+	return loadTemplates(os.DirFS(dir))
+}
+
+// DefaultTemplateFiles 返回内置模板的文件名与内容，用于导出后修改
+func DefaultTemplateFiles() (map[string]string, error) {
+	names, err := fs.Glob(defaultFS, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(defaultFS, name)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Base(name)] = string(data)
+	}
+	return files, nil
+}
+
+// loadTemplates 解析内置模板，override 不为 nil 时其中的同名文件覆盖内置模板
+func loadTemplates(override fs.FS) (*Templates, error) {
+	files, err := DefaultTemplateFiles()
+	if err != nil {
+		return nil, err
+	}
+	overrides := map[string]string{}
+	if override != nil {
+		names, err := fs.Glob(override, "*.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			data, err := fs.ReadFile(override, name)
+			if err != nil {
+				return nil, err
+			}
+			overrides[name] = string(data)
+		}
+	}
+
+	common := template.New("common").Funcs(templateFuncs)
+	for _, text := range []string{files["common.tmpl"], overrides["common.tmpl"]} {
+		if _, err := common.Parse(text); err != nil {
+			return nil, fmt.Errorf("模板 common.tmpl 有误: %w", err)
+		}
+	}
+
+	events := map[string]eventTemplate{}
+	for name := range files {
+		events[strings.TrimSuffix(name, ".tmpl")] = eventTemplate{}
+	}
+	for name := range overrides {
+		events[strings.TrimSuffix(name, ".tmpl")] = eventTemplate{}
+	}
+	delete(events, "common")
+
+	for event := range events {
+		name := event + ".tmpl"
+		t, err := common.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if text, ok := files[name]; ok {
+			if _, err := t.New("default").Parse(text); err != nil {
+				return nil, fmt.Errorf("模板 %s 有误: %w", name, err)
+			}
+		}
+		var et eventTemplate
+		if text, ok := overrides[name]; ok {
+			file, err := t.New(event).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("模板 %s 有误: %w", name, err)
+			}
+			// 没有 define 的文件整个作为正文
+			if file.Tree != nil && strings.TrimSpace(file.Tree.Root.String()) != "" {
+				et.body = file
+			}
+		}
+		et.title = t.Lookup("title")
+		if et.body == nil {
+			et.body = t.Lookup("body")
+		}
+		if et.title == nil || et.body == nil {
+			return nil, fmt.Errorf("模板 %s 缺少标题或正文", name)
+		}
+		events[event] = et
+	}
+	return &Templates{events: events}, nil
+}
+
+// Events 返回有模板的事件
+func (t *Templates) Events() []string {
+	var events []string
+	for event := range t.events {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	return events
+}
+
+// Render 使用 msg.Event 对应的模板与 msg.Data 生成标题与正文。事件没有模板时原样返回。
+func (t *Templates) Render(msg Message) (Message, error) {
+	et, ok := t.events[msg.Event]
+	if !ok {
+		return msg, nil
+	}
+	if msg.Time.IsZero() {
+		msg.Time = now()
+	}
+	data := Data{}
+	for k, v := range commonKeys {
+		data[k] = v
+	}
+	for k, v := range eventKeys[msg.Event] {
+		data[k] = v
+	}
+	for k, v := range msg.Data {
+		data[k] = v
+	}
+	data["event"], data["account"], data["timestamp"] = msg.Event, msg.Account, msg.Time
+	if msg.ScheduleID != 0 {
+		data["schedule_id"] = msg.ScheduleID
+	}
+
+	var title, body strings.Builder
+	if err := et.title.Execute(&title, data); err != nil {
+		return msg, fmt.Errorf("生成 %s 通知标题失败: %w", msg.Event, err)
+	}
+	if err := et.body.Execute(&body, data); err != nil {
+		return msg, fmt.Errorf("生成 %s 通知正文失败: %w", msg.Event, err)
+	}
+	msg.Title = strings.Join(strings.Fields(title.String()), " ")
+	msg.Body = strings.TrimSpace(body.String())
+	return msg, nil
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTemplates(t *testing.T) {
+	templates := DefaultTemplates()
+	for _, event := range []string{EventSignSuccess, EventSignFailure, EventTokenExpired, EventReportSubmitted,
+		EventReportCommented, EventMissedRun, EventMonthlySummary, EventTest} {
+		assert.Contains(t, templates.Events(), event)
+		// 没有数据时也不应输出 <no value>
+		msg, err := templates.Render(Message{Event: event, Account: "user"})
+		require.NoError(t, err, event)
+		assert.NotEmpty(t, msg.Title, event)
+		assert.NotContains(t, msg.Title+msg.Body, "<no value>", event)
+	}
+
+	at := time.Date(2026, 10, 18, 8, 0, 0, 0, time.Local)
+	msg, err := templates.Render(Message{Event: EventSignSuccess, Account: "user", Time: at, Data: Data{
+		"user_name": "张三", "message": "签到成功", "address": "北京东路41号", "latitude": "32.05", "longitude": "118.79"}})
+	require.NoError(t, err)
+	assert.Equal(t, "签到成功", msg.Title)
+	assert.Equal(t, "张三(user) 已于 2026-10-18 08:00:00 签到成功。\n服务器返回：签到成功\n签到地址：北京东路41号\n"+
+		"签到坐标：118.79,32.05(经度,纬度，可在高德地图中查询)", msg.Body)
+
+	msg, err = templates.Render(Message{Event: EventSignFailure, Account: "user", Data: Data{
+		"retry": true, "attempts": 3, "reason": "已达到最大尝试次数", "message": "网络错误"}})
+	require.NoError(t, err)
+	assert.Equal(t, "签到重试失败", msg.Title)
+	assert.Equal(t, "账号 user 共尝试签到 3 次均失败，已达到最大尝试次数，请手动签到。\n错误信息：网络错误", msg.Body)
+
+	// 没有模板的事件原样返回
+	msg, err = templates.Render(Message{Event: "custom", Title: "标题", Body: "正文"})
+	require.NoError(t, err)
+	assert.Equal(t, "标题", msg.Title)
+	assert.Equal(t, "正文", msg.Body)
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644))
+	}
+
+	// 目录不存在时使用内置模板
+	templates, err := LoadTemplates(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	msg, err := templates.Render(Message{Event: EventTest, Account: "user"})
+	require.NoError(t, err)
+	assert.Equal(t, "测试通知", msg.Title)
+
+	// 没有 define 的文件作为正文，标题使用内置模板；只定义标题时正文使用内置模板
+	write("sign_success.tmpl", "{{template \"who\" .}} 已签到：{{.address}} code={{.code}}\n")
+	write("test.tmpl", `{{define "title"}}测试 {{.account}}{{end}}`)
+	write("common.tmpl", `{{define "who"}}[{{.user_name}}]{{end}}`)
+	write("report_commented.tmpl", `{{define "title"}}批阅{{end}}{{define "body"}}{{.comment}}{{end}}`)
+	templates, err = LoadTemplates(dir)
+	require.NoError(t, err)
+
+	msg, err = templates.Render(Message{Event: EventSignSuccess, Account: "user", Data: Data{"user_name": "张三", "address": "北京东路41号"}})
+	require.NoError(t, err)
+	assert.Equal(t, "签到成功", msg.Title)
+	assert.Equal(t, "[张三] 已签到：北京东路41号 code=0", msg.Body)
+
+	msg, err = templates.Render(Message{Event: EventTest, Account: "user", Data: Data{"user_name": "张三"}})
+	require.NoError(t, err)
+	assert.Equal(t, "测试 user", msg.Title)
+	assert.Contains(t, msg.Body, "[张三] 的测试通知", "common.tmpl 对未覆盖的模板同样生效")
+
+	msg, err = templates.Render(Message{Event: EventReportCommented, Data: Data{"comment": "继续保持"}})
+	require.NoError(t, err)
+	assert.Equal(t, "批阅", msg.Title)
+	assert.Equal(t, "继续保持", msg.Body)
+
+	// 模板有误时返回错误
+	write("test.tmpl", `{{.account`)
+	_, err = LoadTemplates(dir)
+	assert.ErrorContains(t, err, "test.tmpl")
+}
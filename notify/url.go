@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// URLParser 将通知 URL 解析为对应渠道的参数
+type URLParser func(u *url.URL) (Config, error)
+
+type scheme struct {
+	kind  string
+	parse URLParser
+}
+
+var schemes = make(map[string]scheme)
+
+// RegisterScheme 注册一种通知 URL 的 scheme，解析后的参数交给 kind 对应的渠道。
+// scheme 重复时 panic。
+func RegisterScheme(name, kind string, parse URLParser) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := schemes[name]; dup {
+		panic("notify: 重复注册通知 URL scheme " + name)
+	}
+	schemes[name] = scheme{kind, parse}
+}
+
+// Schemes 返回所有已注册的通知 URL scheme
+func Schemes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsURL 判断 s 是否为通知 URL(而不是渠道类型)
+func IsURL(s string) bool {
+	return strings.Contains(s, "://")
+}
+
+// ParseURL 将 serverchan://SENDKEY、bark://host/key 等通知 URL 解析为渠道类型与参数
+func ParseURL(raw string) (string, Config, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", nil, fmt.Errorf("通知 URL 格式错误: %w", err)
+	}
+	registryMu.RLock()
+	s, ok := schemes[strings.ToLower(u.Scheme)]
+	registryMu.RUnlock()
+	if !ok {
+		return "", nil, fmt.Errorf("不支持的通知 URL: %s://(支持 %v)", u.Scheme, Schemes())
+	}
+	cfg, err := s.parse(u)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s:// %w", u.Scheme, err)
+	}
+	return s.kind, cfg, nil
+}
+
+// NewFromURL 根据通知 URL 创建通知渠道
+func NewFromURL(raw string) (Notifier, error) {
+	kind, cfg, err := ParseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+	return New(kind, cfg)
+}
+
+// SplitURLs 拆分以空白字符(空格、换行)分隔的多个通知 URL，用于环境变量与 GitHub Secrets
+func SplitURLs(s string) []string {
+	return strings.Fields(s)
+}
+
+// queryConfig 将 URL 的查询参数转换为渠道参数，同名参数只取第一个
+func queryConfig(u *url.URL) Config {
+	cfg := Config{}
+	for k, v := range u.Query() {
+		if len(v) > 0 {
+			cfg[k] = v[0]
+		}
+	}
+	return cfg
+}
+
+// serverURL 返回 URL 的服务地址。scheme 以 s 结尾(如 barks://)时使用 https，否则使用 http；
+// prefix 为主机后的路径前缀(服务部署在子路径时)。
+func serverURL(u *url.URL, secure bool, prefix string) (string, error) {
+	if u.Host == "" {
+		return "", fmt.Errorf("缺少服务地址")
+	}
+	proto := "http"
+	if secure {
+		proto = "https"
+	}
+	return proto + "://" + u.Host + strings.TrimRight(prefix, "/"), nil
+}
+
+// splitPath 拆分 URL 路径，返回最后一段与之前的前缀
+func splitPath(u *url.URL) (prefix, last string) {
+	p := strings.Trim(u.Path, "/")
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		return "/" + p[:i], p[i+1:]
+	}
+	return "", p
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("webhook", func(cfg Config) (Notifier, error) {
+		if cfg["url"] == "" {
+			return nil, errors.New("webhook 缺少 url")
+		}
+		return &Webhook{URL: cfg["url"]}, nil
+	})
+	// webhook://host/path 以 http POST 到 http://host/path，webhooks:// 使用 https
+	for _, name := range []string{"webhook", "webhooks"} {
+		secure := name == "webhooks"
+		RegisterScheme(name, "webhook", func(u *url.URL) (Config, error) {
+			target := *u
+			target.Scheme = "http"
+			if secure {
+				target.Scheme = "https"
+			}
+			if target.Host == "" {
+				return nil, errors.New("缺少服务地址")
+			}
+			return Config{"url": target.String()}, nil
+		})
+	}
+}
+
+// Webhook 将通知以 JSON 格式 POST 到任意地址
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// WebhookPayload 是 Webhook 发送的 JSON 内容
+type WebhookPayload struct {
+	Event   string `json:"event"`
+	Account string `json:"account"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	Time    string `json:"time"` // RFC3339 格式
+}
+
+// Send 发送一条通知，HTTP 状态不为 2xx 时返回 ProviderError
+func (w *Webhook) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, w.Client, "webhook", strings.TrimSpace(w.URL), WebhookPayload{
+		Event:   msg.Event,
+		Account: msg.Account,
+		Title:   msg.Title,
+		Body:    msg.Body,
+		Time:    msg.Time.Format(time.RFC3339),
+	}, nil)
+}
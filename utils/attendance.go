@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"time"
+)
+
+// AttendanceDay 是月度考勤汇总中的一天
+type AttendanceDay struct {
+	Date      time.Time // 当天零点(汇总使用的时区)
+	Signed    bool
+	SignedAt  time.Time // 首次签到成功的时间，今日已签到的记录不含签到时间时为确认的时间
+	Address   string
+	Failures  int // 失败的签到尝试次数
+	LastError string
+}
+
+// MonthlyAttendance 汇总账号在 month 所在月份(按 month 的时区划分日期)每天的签到情况，
+// 当月只汇总到今天，按日期顺序返回。
+func MonthlyAttendance(account string, month time.Time) ([]AttendanceDay, error) {
+	loc := month.Location()
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 1, 0)
+	if today := time.Now().In(loc); today.Before(end) {
+		end = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	}
+
+	var days []AttendanceDay
+	index := make(map[string]int)
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		index[d.Format("2006-01-02")] = len(days)
+		days = append(days, AttendanceDay{Date: d})
+	}
+	if len(days) == 0 {
+		return nil, nil
+	}
+
+	logs, err := QuerySignLogs(SignLogFilter{Account: account, From: start, To: end})
+	if err != nil {
+		return nil, err
+	}
+	// 按时间正序处理，保留每天首次成功的记录
+	for i := len(logs) - 1; i >= 0; i-- {
+		l := logs[i]
+		day := &days[index[l.SignedAt.In(loc).Format("2006-01-02")]]
+		switch {
+		case day.Signed:
+		case l.Status == SignStatusSuccess || l.Status == SignStatusAlreadySigned:
+			day.Signed, day.SignedAt, day.Address = true, l.SignedAt.In(loc), l.Address
+		default:
+			day.Failures++
+			day.LastError = l.Message
+		}
+	}
+	return days, nil
+}
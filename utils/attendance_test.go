@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonthlyAttendance(t *testing.T) {
+	openTestDB(t)
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(day, hour int) time.Time { return time.Date(2025, 9, day, hour, 0, 0, 0, loc) }
+	for _, l := range []SignLog{
+		{Account: "user", SignedAt: at(1, 8), Status: SignStatusFailed, Message: "网络错误"},
+		{Account: "user", SignedAt: at(1, 9), Status: SignStatusSuccess, Address: "南京"},
+		{Account: "user", SignedAt: at(1, 10), Status: SignStatusSuccess, Address: "上海"},
+		{Account: "user", SignedAt: at(2, 8), Status: SignStatusFailed, Message: "不在签到范围内"},
+		{Account: "user", SignedAt: at(3, 8), Status: SignStatusAlreadySigned},
+		// 北京时间 10 月 1 日 7 点，不属于 9 月
+		{Account: "user", SignedAt: time.Date(2025, 9, 30, 23, 0, 0, 0, time.UTC), Status: SignStatusSuccess},
+		{Account: "other", SignedAt: at(4, 8), Status: SignStatusSuccess},
+	} {
+		_, err := RecordSignLog(l)
+		require.NoError(t, err)
+	}
+
+	days, err := MonthlyAttendance("user", at(15, 0))
+	require.NoError(t, err)
+	require.Len(t, days, 30)
+	assert.Equal(t, AttendanceDay{Date: at(1, 0), Signed: true, SignedAt: at(1, 9), Address: "南京", Failures: 1, LastError: "网络错误"}, days[0])
+	assert.Equal(t, AttendanceDay{Date: at(2, 0), Failures: 1, LastError: "不在签到范围内"}, days[1])
+	assert.True(t, days[2].Signed, "今日已签到视为已签到")
+	assert.False(t, days[3].Signed)
+	assert.False(t, days[29].Signed)
+
+	// 本月只汇总到今天
+	now := time.Now().In(loc)
+	days, err = MonthlyAttendance("user", now)
+	require.NoError(t, err)
+	assert.Len(t, days, now.Day())
+}
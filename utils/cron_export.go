@@ -0,0 +1,284 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronStarBit 是 robfig/cron 在字段为 * 或 ? 时设置的标志位
+const cronStarBit = 1 << 63
+
+// CronFields 是 cron 表达式展开后各字段的取值，用于把定时任务导出到 crontab、
+// systemd、GitHub Actions、Kubernetes 等其他调度器。字段为 nil 表示任意值(*)。
+type CronFields struct {
+	Minute []int
+	Hour   []int
+	Dom    []int
+	Month  []int
+	Dow    []int // 0 为周日
+
+	// Every 不为 0 时表示 @every 固定间隔，此时其余字段为空。
+	Every time.Duration
+	// Location 是表达式中通过 CRON_TZ= 指定的时区，未指定时为 nil。
+	Location *time.Location
+}
+
+// ParseCronFields 解析 cron 表达式(与 ParseCronExpr 支持的语法相同)并展开各字段
+func ParseCronFields(expr string) (CronFields, error) {
+	sched, err := ParseCronExpr(expr)
+	if err != nil {
+		return CronFields{}, err
+	}
+	switch s := sched.(type) {
+	case *cron.SpecSchedule:
+		f := CronFields{
+			Minute: cronBits(s.Minute, 0, 59),
+			Hour:   cronBits(s.Hour, 0, 23),
+			Dom:    cronBits(s.Dom, 1, 31),
+			Month:  cronBits(s.Month, 1, 12),
+			Dow:    cronBits(s.Dow, 0, 6),
+		}
+		if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+			f.Location = s.Location
+		}
+		return f, nil
+	case cron.ConstantDelaySchedule:
+		return CronFields{Every: s.Delay}, nil
+	}
+	return CronFields{}, fmt.Errorf("cron 表达式 %q 无法导出", expr)
+}
+
+// OnceFields 返回在 at 所在的月、日、时、分执行的字段，用于导出一次性任务。
+// 标准 cron 不含年份，导出后每年都会在该时间执行。
+func OnceFields(at time.Time) CronFields {
+	return CronFields{
+		Minute: []int{at.Minute()},
+		Hour:   []int{at.Hour()},
+		Dom:    []int{at.Day()},
+		Month:  []int{int(at.Month())},
+	}
+}
+
+// cronBits 将 robfig/cron 的位图字段转换为取值列表，* 返回 nil
+func cronBits(bits uint64, lo, hi int) []int {
+	if bits&cronStarBit != 0 {
+		return nil
+	}
+	var vals []int
+	for i := lo; i <= hi; i++ {
+		if bits&(1<<uint(i)) != 0 {
+			vals = append(vals, i)
+		}
+	}
+	return vals
+}
+
+// expand 将 @every 固定间隔近似为按整点对齐的字段，只支持能整除一小时或一天的间隔
+func (f CronFields) expand() (CronFields, error) {
+	if f.Every == 0 {
+		return f, nil
+	}
+	if f.Every%time.Minute == 0 {
+		m := int(f.Every / time.Minute)
+		switch {
+		case m < 60 && 60%m == 0:
+			return CronFields{Minute: steps(0, 59, m), Location: f.Location}, nil
+		case m%60 == 0 && 24%(m/60) == 0:
+			return CronFields{Minute: []int{0}, Hour: steps(0, 23, m/60), Location: f.Location}, nil
+		}
+	}
+	return CronFields{}, fmt.Errorf("@every %s 无法转换为标准 cron 表达式(间隔需能整除一小时或一天)", f.Every)
+}
+
+func steps(lo, hi, step int) []int {
+	var vals []int
+	for i := lo; i <= hi; i += step {
+		vals = append(vals, i)
+	}
+	return vals
+}
+
+// Crontab 返回 5 段标准 cron 表达式
+func (f CronFields) Crontab() (string, error) {
+	f, err := f.expand()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		formatCronField(f.Minute, 0, 59, true, "-", nil),
+		formatCronField(f.Hour, 0, 23, true, "-", nil),
+		formatCronField(f.Dom, 1, 31, false, "-", nil),
+		formatCronField(f.Month, 1, 12, true, "-", nil),
+		formatCronField(f.Dow, 0, 6, false, "-", nil),
+	}, " "), nil
+}
+
+var systemdWeekdays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// OnCalendar 返回 systemd 定时器的 OnCalendar 表达式(不含时区)。
+// cron 中同时限定日期与星期时满足其一即执行，而 systemd 要求同时满足，因此拆成两条。
+func (f CronFields) OnCalendar() ([]string, error) {
+	if f.Every != 0 {
+		return nil, errors.New("@every 固定间隔应使用 OnUnitActiveSec")
+	}
+	pad := func(v int) string { return fmt.Sprintf("%02d", v) }
+	weekday := func(v int) string { return systemdWeekdays[v] }
+	clock := formatCronField(f.Hour, 0, 23, true, "..", pad) + ":" + formatCronField(f.Minute, 0, 59, true, "..", pad) + ":00"
+	month := formatCronField(f.Month, 1, 12, true, "..", pad)
+	dom := formatCronField(f.Dom, 1, 31, false, "..", pad)
+	switch {
+	case f.Dow == nil:
+		return []string{"*-" + month + "-" + dom + " " + clock}, nil
+	case f.Dom == nil:
+		return []string{formatCronField(f.Dow, 0, 6, false, "..", weekday) + " *-" + month + "-* " + clock}, nil
+	}
+	return []string{
+		"*-" + month + "-" + dom + " " + clock,
+		formatCronField(f.Dow, 0, 6, false, "..", weekday) + " *-" + month + "-* " + clock,
+	}, nil
+}
+
+// Shift 将每次执行时间平移 d(须为整分钟)，用于换算到其他时区，如北京时间换算为 UTC 时 d 为 -8h。
+// 平移后小时与分钟的组合可能无法用一条表达式表示，因此可能返回多条；
+// 跨天时只能调整星期，限定了日期或月份时返回错误。
+func (f CronFields) Shift(d time.Duration) ([]CronFields, error) {
+	f, err := f.expand()
+	if err != nil {
+		return nil, err
+	}
+	if d%time.Minute != 0 {
+		return nil, fmt.Errorf("时差 %s 不是整分钟", d)
+	}
+	shift := int(d / time.Minute)
+	if shift == 0 {
+		return []CronFields{f}, nil
+	}
+	minutes, hours := f.Minute, f.Hour
+	if minutes == nil {
+		minutes = steps(0, 59, 1)
+	}
+	if hours == nil {
+		hours = steps(0, 23, 1)
+	}
+
+	// 按(跨越的天数, 小时)分组分钟；不限定星期时跨天不影响结果
+	type slot struct{ days, hour int }
+	grouped := make(map[slot][]int)
+	for _, h := range hours {
+		for _, m := range minutes {
+			total := h*60 + m + shift
+			days := total / 1440
+			if total < 0 {
+				days = -((-total + 1439) / 1440)
+			}
+			total -= days * 1440
+			if days != 0 && (f.Dom != nil || f.Month != nil) {
+				return nil, errors.New("换算时区后跨天，而任务限定了日期或月份，无法转换")
+			}
+			if f.Dow == nil {
+				days = 0
+			}
+			s := slot{days, total / 60}
+			grouped[s] = append(grouped[s], total%60)
+		}
+	}
+
+	// 同一天内分钟取值相同的小时合并为一条
+	type key struct {
+		days    int
+		minutes string
+	}
+	merged := make(map[key]*CronFields)
+	var keys []key
+	for s, mins := range grouped {
+		sort.Ints(mins)
+		k := key{s.days, fmt.Sprint(mins)}
+		c, ok := merged[k]
+		if !ok {
+			c = &CronFields{Minute: mins, Dom: f.Dom, Month: f.Month, Location: f.Location}
+			if f.Dow != nil {
+				for _, w := range f.Dow {
+					c.Dow = append(c.Dow, ((w+s.days)%7+7)%7)
+				}
+				sort.Ints(c.Dow)
+			}
+			merged[k] = c
+			keys = append(keys, k)
+		}
+		c.Hour = append(c.Hour, s.hour)
+	}
+
+	var out []CronFields
+	for _, k := range keys {
+		c := merged[k]
+		sort.Ints(c.Hour)
+		if len(c.Minute) == 60 {
+			c.Minute = nil
+		}
+		if len(c.Hour) == 24 {
+			c.Hour = nil
+		}
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return fieldMin(out[i].Hour) < fieldMin(out[j].Hour) ||
+			fieldMin(out[i].Hour) == fieldMin(out[j].Hour) && fieldMin(out[i].Minute) < fieldMin(out[j].Minute)
+	})
+	return out, nil
+}
+
+func fieldMin(vals []int) int {
+	if len(vals) == 0 {
+		return -1
+	}
+	return vals[0]
+}
+
+// formatCronField 将取值列表格式化为 cron 字段：nil 为 *，从下限开始的等差数列写作步长，
+// 连续三个及以上的值写作区间。name 不为空时用于格式化单个值。
+func formatCronField(vals []int, lo, hi int, step bool, rangeSep string, name func(int) string) string {
+	if vals == nil {
+		return "*"
+	}
+	if name == nil {
+		name = strconv.Itoa
+	}
+	if step && len(vals) > 1 && vals[0] == lo {
+		d := vals[1] - vals[0]
+		uniform := d > 1 && vals[len(vals)-1]+d > hi
+		for i := 2; uniform && i < len(vals); i++ {
+			uniform = vals[i]-vals[i-1] == d
+		}
+		if uniform {
+			start := "*"
+			if rangeSep != "-" {
+				// systemd 的步长写作 起始值/步长
+				start = name(lo)
+			}
+			return start + "/" + strconv.Itoa(d)
+		}
+	}
+	var parts []string
+	for i := 0; i < len(vals); {
+		j := i
+		for j+1 < len(vals) && vals[j+1] == vals[j]+1 {
+			j++
+		}
+		switch {
+		case j-i >= 2:
+			parts = append(parts, name(vals[i])+rangeSep+name(vals[j]))
+		case j > i:
+			parts = append(parts, name(vals[i]), name(vals[j]))
+		default:
+			parts = append(parts, name(vals[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
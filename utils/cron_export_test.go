@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronFieldsExport(t *testing.T) {
+	cases := []struct {
+		expr       string
+		crontab    string
+		onCalendar []string
+	}{
+		{"30 8 * * *", "30 8 * * *", []string{"*-*-* 08:30:00"}},
+		{"*/15 9-17 * * 1-5", "*/15 9-17 * * 1-5", []string{"Mon..Fri *-*-* 09..17:00/15:00"}},
+		{"@weekly", "0 0 * * 0", []string{"Sun *-*-* 00:00:00"}},
+		{"0 20 1,15 * 5", "0 20 1,15 * 5", []string{"*-*-01,15 20:00:00", "Fri *-*-* 20:00:00"}},
+		{"@every 30m", "*/30 * * * *", nil},
+		{"@every 6h", "0 */6 * * *", nil},
+	}
+	for _, c := range cases {
+		f, err := ParseCronFields(c.expr)
+		require.NoError(t, err, c.expr)
+		got, err := f.Crontab()
+		require.NoError(t, err, c.expr)
+		assert.Equal(t, c.crontab, got, c.expr)
+		if c.onCalendar != nil {
+			cal, err := f.OnCalendar()
+			require.NoError(t, err, c.expr)
+			assert.Equal(t, c.onCalendar, cal, c.expr)
+		}
+	}
+
+	f, err := ParseCronFields("@every 7m")
+	require.NoError(t, err)
+	assert.Equal(t, 7*time.Minute, f.Every)
+	_, err = f.Crontab()
+	assert.Error(t, err, "无法整除一小时的间隔不能转换")
+
+	f, err = ParseCronFields("CRON_TZ=Asia/Tokyo 0 9 * * *")
+	require.NoError(t, err)
+	require.NotNil(t, f.Location)
+	assert.Equal(t, "Asia/Tokyo", f.Location.String())
+}
+
+func TestCronFieldsShift(t *testing.T) {
+	crontabs := func(expr string, d time.Duration) []string {
+		f, err := ParseCronFields(expr)
+		require.NoError(t, err)
+		shifted, err := f.Shift(d)
+		require.NoError(t, err)
+		var out []string
+		for _, s := range shifted {
+			c, err := s.Crontab()
+			require.NoError(t, err)
+			out = append(out, c)
+		}
+		return out
+	}
+
+	// 北京时间换算为 UTC
+	assert.Equal(t, []string{"30 0 * * *"}, crontabs("30 8 * * *", -8*time.Hour))
+	assert.Equal(t, []string{"0 4,10,22 * * *"}, crontabs("0 6,12,18 * * *", -8*time.Hour))
+	// 跨天时星期随之调整
+	assert.Equal(t, []string{"0 4 * * 1-5", "0 22 * * 0-4"}, crontabs("0 6,12 * * 1-5", -8*time.Hour))
+	// 非整小时的时差
+	assert.Equal(t, []string{"30 3 * * *"}, crontabs("0 9 * * *", -5*time.Hour-30*time.Minute))
+
+	f, err := ParseCronFields("0 6 1 * *")
+	require.NoError(t, err)
+	_, err = f.Shift(-8 * time.Hour)
+	assert.Error(t, err, "限定日期时跨天无法换算")
+
+	once := OnceFields(time.Date(2026, 10, 31, 20, 0, 0, 0, time.UTC))
+	c, err := once.Crontab()
+	require.NoError(t, err)
+	assert.Equal(t, "0 20 31 10 *", c)
+}
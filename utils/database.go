@@ -1,90 +1,102 @@
 package utils
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	_ "github.com/mattn/go-sqlite3"
 	"log"
-	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"xixunyunsign/xixunyun"
 )
 
 var db *sql.DB
 
+// DBPath is the path of the SQLite database file.
+var DBPath = "config.db"
+
+// Stateless 为 true 时使用内存数据库，不读写 DBPath，进程退出后所有数据随之丢弃。
+// 适合每次运行都从空目录开始的 CI(如 GitHub Actions)，通过 --stateless 参数或环境变量 XIXUN_STATELESS=1 开启。
+var Stateless, _ = strconv.ParseBool(os.Getenv("XIXUN_STATELESS"))
+
+// memoryDSN 是无状态模式使用的内存数据库，cache=shared 让连接池中的所有连接共用同一个库。
+const memoryDSN = "file:xixun-stateless?mode=memory&cache=shared"
+
 // SchoolInfo represents the structure of school data.
 type SchoolInfo struct {
 	SchoolID   string `json:"school_id"`
 	SchoolName string `json:"school_name"`
 }
 
-// InitDB initializes the database and creates the required tables.
+// InitDB opens the database and, unless AutoMigrate is disabled, applies pending schema migrations.
 func InitDB() error {
-	// Set the database file path to "config.db"
-	dbPath := "config.db"
-
-	// Open the database connection
+	// Open the database connection. 多个进程共用数据库时等待写锁，而不是立即返回 database is locked。
+	dsn := DBPath
+	if Stateless {
+		dsn = memoryDSN
+	}
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_busy_timeout=5000"
+	}
 	var err error
-	db, err = sql.Open("sqlite3", dbPath)
+	db, err = sql.Open("sqlite3", dsn)
 	if err != nil {
 		return err
 	}
 
-	// Create the users table
-	createUserTableSQL := `
-    CREATE TABLE IF NOT EXISTS users (
-        account TEXT PRIMARY KEY,
-        password TEXT,
-        token TEXT,
-        latitude TEXT,
-        longitude TEXT,
-        bind_phone TEXT,
-        user_number TEXT,
-        user_name TEXT,
-        school_id INT,
-        sex TEXT,
-        class_name TEXT,
-        entrance_year TEXT,
-        graduation_year TEXT
-    );
-    `
-	_, err = db.Exec(createUserTableSQL)
-	if err != nil {
-		return err
+	if !AutoMigrate {
+		return nil
 	}
+	_, err = Migrate()
+	return err
+}
 
-	// Create the school_info table (updated to include city_name and city_id)
-	createSchoolTableSQL := `
-    CREATE TABLE IF NOT EXISTS school_info (
-        school_id TEXT PRIMARY KEY,
-        school_name TEXT,
-        city_name TEXT,  -- Added city_name
-        city_id TEXT     -- Added city_id
-    );
-    `
-	_, err = db.Exec(createSchoolTableSQL)
-	if err != nil {
-		return err
+// getMeta reads a value from the app_meta table, returning "" when the key is absent.
+func getMeta(key string) (string, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return "", err
+		}
+	}
+	var value string
+	err := db.QueryRow(`SELECT value FROM app_meta WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
 	}
+	return value, err
+}
+
+// setMetaTx writes a value to the app_meta table inside a transaction.
+func setMetaTx(tx *sql.Tx, key, value string) error {
+	_, err := tx.Exec(`INSERT INTO app_meta (key, value) VALUES (?, ?)
+        ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+// deleteMetaTx removes a key from the app_meta table inside a transaction.
+func deleteMetaTx(tx *sql.Tx, key string) error {
+	_, err := tx.Exec(`DELETE FROM app_meta WHERE key = ?`, key)
+	return err
+}
 
-	// Create schedules table if it doesn't exist
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS schedules (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        account TEXT,
-        address TEXT,
-        latitude TEXT,
-        longitude TEXT,
-        province TEXT,
-        city TEXT,
-        remark TEXT,
-        comment TEXT,
-        cron_expr TEXT,
-        enabled INTEGER DEFAULT 1
-    )`)
+// withTx runs fn inside a transaction, committing on success and rolling back on error.
+func withTx(fn func(tx *sql.Tx) error) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("创建 schedules 表失败: %v", err)
+		return err
 	}
-
-	return nil
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 // SaveSchoolInfo saves or updates the school information in the database.
@@ -108,34 +120,17 @@ func SaveSchoolInfo(cityName, cityID, schoolID, schoolName string) error {
 	return err
 }
 
-// FetchAndSaveSchoolData fetches the school data from the given API and saves it to the database.
-func FetchAndSaveSchoolData() error {
-	// Make the GET request to the API
-	resp, err := http.Get("https://api.xixunyun.com/login/schoolmap")
+// FetchAndSaveSchoolData fetches the school data through the given client and saves it to the database.
+func FetchAndSaveSchoolData(client *xixunyun.Client) error {
+	cities, err := client.SchoolMap(context.Background())
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	// Decode the JSON response
-	var result struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-		Data    []struct {
-			CityName string       `json:"name"`
-			CityId   string       `json:"id"`
-			Schools  []SchoolInfo `json:"list"`
-		} `json:"data"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
-	}
 
 	// Loop through the data and save each school info
-	for _, group := range result.Data {
+	for _, group := range cities {
 		for _, school := range group.Schools {
-			if err := SaveSchoolInfo(group.CityName, group.CityId, school.SchoolID, school.SchoolName); err != nil {
+			if err := SaveSchoolInfo(group.CityName, group.CityID.String(), school.SchoolID.String(), school.SchoolName); err != nil {
 				log.Printf("Error saving school %s: %v", school.SchoolName, err)
 			}
 		}
@@ -172,7 +167,17 @@ func SaveUser(account, password, token, latitude, longitude, bindPhone, userNumb
         graduation_year = excluded.graduation_year;
     `
 
-	_, err := db.Exec(insertSQL, account, password, token, latitude, longitude, bindPhone, userNumber, userName, schoolID, sex, className, entranceYear, graduationYear)
+	// Encrypt the password and token when at-rest encryption is enabled
+	password, err := sealSecret(password)
+	if err != nil {
+		return err
+	}
+	token, err = sealSecret(token)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(insertSQL, account, password, token, latitude, longitude, bindPhone, userNumber, userName, schoolID, sex, className, entranceYear, graduationYear)
 	return err
 }
 
@@ -185,14 +190,49 @@ func GetUser(account string) (token, latitude, longitude string, err error) {
 	}
 	querySQL := `SELECT token, latitude, longitude FROM users WHERE account = ?;`
 	row := db.QueryRow(querySQL, account)
-	err = row.Scan(&token, &latitude, &longitude)
+	if err = row.Scan(&token, &latitude, &longitude); err != nil {
+		return
+	}
+	token, err = openSecret(token)
+	return
+}
+
+// GetCredentials retrieves the stored password and school ID used to log in again.
+func GetCredentials(account string) (password, schoolID string, err error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return "", "", err
+		}
+	}
+	querySQL := `SELECT IFNULL(password, ''), IFNULL(school_id, '') FROM users WHERE account = ?;`
+	if err = db.QueryRow(querySQL, account).Scan(&password, &schoolID); err != nil {
+		return
+	}
+	password, err = openSecret(password)
 	return
 }
 
+// UpdateToken updates the token for a given account without touching other columns.
+func UpdateToken(account, token string) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+	token, err := sealSecret(token)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE users SET token = ? WHERE account = ?;`, token, account)
+	return err
+}
+
 // CloseDB closes the database connection.
 func CloseDB() error {
 	if db != nil {
-		return db.Close()
+		err := db.Close()
+		db = nil
+		return err
 	}
 	return nil
 }
@@ -259,6 +299,21 @@ func GetAdditionalUserData(account string) (map[string]string, error) {
 	}, nil
 }
 
+// GetUserProfile 返回账号登录时保存的姓名与班级，用于通知模板。
+func GetUserProfile(account string) (userName, className string, err error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return "", "", err
+		}
+	}
+	var name, class sql.NullString
+	err = db.QueryRow(`SELECT user_name, class_name FROM users WHERE account = ?`, account).Scan(&name, &class)
+	if err != nil {
+		return "", "", err
+	}
+	return name.String, class.String, nil
+}
+
 // SearchSchoolID searches for all school IDs by school name using fuzzy matching.
 func SearchSchoolID(schoolName string) ([]SchoolInfo, error) {
 	if db == nil {
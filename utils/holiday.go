@@ -0,0 +1,339 @@
+package utils
+
+import (
+	"bufio"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BuiltinCalendar 是内置的中国法定节假日日历名称。
+const BuiltinCalendar = "cn"
+
+// 日历中日期的类型
+const (
+	DayTypeHoliday = "holiday" // 放假
+	DayTypeWorkday = "workday" // 调休上班
+)
+
+// 定时任务的签到日期策略
+const (
+	DayPolicyEveryday = "everyday" // 每天
+	DayPolicyWorkdays = "workdays" // 按内置日历的工作日（含调休上班的周末）
+	// DayPolicyCalendarPrefix 后接日历名称，如 calendar:school，按导入的日历判断工作日。
+	DayPolicyCalendarPrefix = "calendar:"
+)
+
+// calendarDateLayout 是日历中日期的格式
+const calendarDateLayout = "2006-01-02"
+
+//go:embed holidays/cn.json
+var builtinCalendarJSON []byte
+
+// CalendarDay 是日历中的一条记录，End 不为空时表示 Date 到 End（含）的连续多天。
+type CalendarDay struct {
+	Date string `json:"date"`
+	End  string `json:"end,omitempty"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// CalendarFile 是日历 JSON 文件的格式，与内置日历 holidays/cn.json 相同。
+type CalendarFile struct {
+	Name string        `json:"name"`
+	Days []CalendarDay `json:"days"`
+}
+
+var (
+	builtinOnce sync.Once
+	builtinDays map[string]CalendarDay
+	builtinErr  error
+)
+
+// builtinCalendar 返回内置日历中按日期索引的记录。
+func builtinCalendar() (map[string]CalendarDay, error) {
+	builtinOnce.Do(func() {
+		var f CalendarFile
+		if builtinErr = json.Unmarshal(builtinCalendarJSON, &f); builtinErr != nil {
+			return
+		}
+		var days []CalendarDay
+		if days, builtinErr = ExpandCalendarDays(f.Days); builtinErr != nil {
+			return
+		}
+		builtinDays = make(map[string]CalendarDay, len(days))
+		for _, d := range days {
+			builtinDays[d.Date] = d
+		}
+	})
+	return builtinDays, builtinErr
+}
+
+// ValidDayPolicy 判断是否为支持的签到日期策略
+func ValidDayPolicy(policy string) bool {
+	switch policy {
+	case DayPolicyEveryday, DayPolicyWorkdays:
+		return true
+	}
+	return strings.HasPrefix(policy, DayPolicyCalendarPrefix) && len(policy) > len(DayPolicyCalendarPrefix)
+}
+
+// dayPolicyCalendar 返回签到日期策略使用的日历，每天签到时返回空字符串。
+func dayPolicyCalendar(policy string) string {
+	if policy == DayPolicyWorkdays {
+		return BuiltinCalendar
+	}
+	return strings.TrimPrefix(policy, DayPolicyCalendarPrefix)
+}
+
+// ExpandCalendarDays 校验日历记录并将多天的记录展开为逐日记录。
+func ExpandCalendarDays(days []CalendarDay) ([]CalendarDay, error) {
+	var expanded []CalendarDay
+	for _, d := range days {
+		if d.Type != DayTypeHoliday && d.Type != DayTypeWorkday {
+			return nil, fmt.Errorf("日期 %s 的类型 %q 无效，只能为 holiday 或 workday", d.Date, d.Type)
+		}
+		start, err := time.Parse(calendarDateLayout, d.Date)
+		if err != nil {
+			return nil, fmt.Errorf("日期 %q 格式不正确: %v", d.Date, err)
+		}
+		end := start
+		if d.End != "" {
+			if end, err = time.Parse(calendarDateLayout, d.End); err != nil {
+				return nil, fmt.Errorf("日期 %q 格式不正确: %v", d.End, err)
+			}
+			if end.Before(start) {
+				return nil, fmt.Errorf("结束日期 %s 早于开始日期 %s", d.End, d.Date)
+			}
+		}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			expanded = append(expanded, CalendarDay{Date: day.Format(calendarDateLayout), Type: d.Type, Name: d.Name})
+		}
+	}
+	return expanded, nil
+}
+
+// ParseCalendarJSON 读取 JSON 格式的日历
+func ParseCalendarJSON(r io.Reader) (CalendarFile, error) {
+	var f CalendarFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return f, fmt.Errorf("解析日历 JSON 失败: %v", err)
+	}
+	return f, nil
+}
+
+// ParseCalendarICS 读取 iCalendar(.ics) 格式的节假日日历。每个 VEVENT 的 DTSTART 到 DTEND（不含）
+// 为一段日期，SUMMARY 中含有“班”字（如“补班”、“调休上班”）的视为工作日，其余视为放假。
+func ParseCalendarICS(r io.Reader) ([]CalendarDay, error) {
+	// 展开折叠行：以空格或制表符开头的行是上一行的延续
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var (
+		days    []CalendarDay
+		inEvent bool
+		start   string
+		end     string
+		summary string
+	)
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if i := strings.Index(name, ";"); i >= 0 {
+			name = name[:i]
+		}
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent, start, end, summary = true, "", "", ""
+			}
+		case "DTSTART":
+			start = value
+		case "DTEND":
+			end = value
+		case "SUMMARY":
+			summary = value
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+			day, err := icsEventDay(start, end, summary)
+			if err != nil {
+				return nil, err
+			}
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// icsEventDay 将 VEVENT 的起止日期转换为日历记录，DTEND 不包含在内。
+func icsEventDay(start, end, summary string) (CalendarDay, error) {
+	if len(start) < 8 {
+		return CalendarDay{}, fmt.Errorf("事件 %q 的 DTSTART %q 无效", summary, start)
+	}
+	s, err := time.Parse("20060102", start[:8])
+	if err != nil {
+		return CalendarDay{}, fmt.Errorf("事件 %q 的 DTSTART %q 无效: %v", summary, start, err)
+	}
+	day := CalendarDay{Date: s.Format(calendarDateLayout), Type: DayTypeHoliday, Name: summary}
+	if strings.Contains(summary, "班") {
+		day.Type = DayTypeWorkday
+	}
+	if len(end) >= 8 {
+		e, err := time.Parse("20060102", end[:8])
+		if err != nil {
+			return CalendarDay{}, fmt.Errorf("事件 %q 的 DTEND %q 无效: %v", summary, end, err)
+		}
+		if last := e.AddDate(0, 0, -1); last.After(s) {
+			day.End = last.Format(calendarDateLayout)
+		}
+	}
+	return day, nil
+}
+
+// ImportCalendar 将日历记录导入数据库中的指定日历，replace 为 true 时先清空该日历。
+// 导入的记录优先于内置日历中同一天的记录。返回导入的天数。
+func ImportCalendar(calendar string, days []CalendarDay, replace bool) (int, error) {
+	expanded, err := ExpandCalendarDays(days)
+	if err != nil {
+		return 0, err
+	}
+	err = withTx(func(tx *sql.Tx) error {
+		if replace {
+			if _, err := tx.Exec(`DELETE FROM holidays WHERE calendar = ?`, calendar); err != nil {
+				return err
+			}
+		}
+		for _, d := range expanded {
+			_, err := tx.Exec(`INSERT INTO holidays (calendar, date, type, name) VALUES (?, ?, ?, ?)
+                ON CONFLICT(calendar, date) DO UPDATE SET type = excluded.type, name = excluded.name`,
+				calendar, d.Date, d.Type, d.Name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("导入日历失败: %v", err)
+	}
+	return len(expanded), nil
+}
+
+// CalendarDays 返回日历在指定年份（0 为全部）的逐日记录，包含内置日历与导入的记录。
+func CalendarDays(calendar string, year int) ([]CalendarDay, error) {
+	merged := map[string]CalendarDay{}
+	if calendar == BuiltinCalendar {
+		builtin, err := builtinCalendar()
+		if err != nil {
+			return nil, err
+		}
+		for date, d := range builtin {
+			merged[date] = d
+		}
+	}
+
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := db.Query(`SELECT date, type, IFNULL(name, '') FROM holidays WHERE calendar = ?`, calendar)
+	if err != nil {
+		return nil, fmt.Errorf("查询日历失败: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d CalendarDay
+		if err := rows.Scan(&d.Date, &d.Type, &d.Name); err != nil {
+			return nil, fmt.Errorf("读取日历失败: %v", err)
+		}
+		merged[d.Date] = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	days := make([]CalendarDay, 0, len(merged))
+	prefix := fmt.Sprintf("%04d-", year)
+	for date, d := range merged {
+		if year == 0 || strings.HasPrefix(date, prefix) {
+			days = append(days, d)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days, nil
+}
+
+// lookupCalendarDay 查询日历中某一天的记录，导入的记录优先于内置日历。
+func lookupCalendarDay(calendar, date string) (CalendarDay, bool, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return CalendarDay{}, false, err
+		}
+	}
+	d := CalendarDay{Date: date}
+	err := db.QueryRow(`SELECT type, IFNULL(name, '') FROM holidays WHERE calendar = ? AND date = ?`, calendar, date).Scan(&d.Type, &d.Name)
+	if err == nil {
+		return d, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return CalendarDay{}, false, err
+	}
+	if calendar == BuiltinCalendar {
+		builtin, err := builtinCalendar()
+		if err != nil {
+			return CalendarDay{}, false, err
+		}
+		d, ok := builtin[date]
+		return d, ok, nil
+	}
+	return CalendarDay{}, false, nil
+}
+
+// IsWorkday 按日历判断某天是否为工作日：日历中有记录时以记录为准，否则周一至周五为工作日。
+// 返回的 reason 用于日志说明。
+func IsWorkday(calendar string, day time.Time) (bool, string, error) {
+	d, ok, err := lookupCalendarDay(calendar, day.Format(calendarDateLayout))
+	if err != nil {
+		return false, "", err
+	}
+	if ok {
+		return d.Type == DayTypeWorkday, d.Name, nil
+	}
+	switch day.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false, "周末", nil
+	}
+	return true, "工作日", nil
+}
+
+// ShouldSignOn 按签到日期策略判断某天是否需要签到，返回的 reason 用于日志说明。
+func ShouldSignOn(policy string, day time.Time) (bool, string, error) {
+	calendar := dayPolicyCalendar(policy)
+	if policy == "" || policy == DayPolicyEveryday || calendar == "" {
+		return true, "", nil
+	}
+	return IsWorkday(calendar, day)
+}
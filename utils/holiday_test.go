@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSignOnWorkdays(t *testing.T) {
+	openTestDB(t)
+	cases := []struct {
+		date string
+		want bool
+	}{
+		{"2026-10-01", false}, // 国庆节（周四）
+		{"2026-10-10", true},  // 国庆节调休上班（周六）
+		{"2026-10-11", false}, // 普通周日
+		{"2026-10-12", true},  // 普通周一
+		{"2026-02-14", true},  // 春节调休上班（周六）
+		{"2026-02-16", false}, // 春节（周一）
+	}
+	for _, c := range cases {
+		day, err := time.Parse(calendarDateLayout, c.date)
+		require.NoError(t, err)
+		got, _, err := ShouldSignOn(DayPolicyWorkdays, day)
+		require.NoError(t, err)
+		assert.Equal(t, c.want, got, c.date)
+
+		got, _, err = ShouldSignOn(DayPolicyEveryday, day)
+		require.NoError(t, err)
+		assert.True(t, got, c.date)
+	}
+}
+
+func TestImportCalendar(t *testing.T) {
+	openTestDB(t)
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261012\r\nDTEND;VALUE=DATE:20261014\r\nSUMMARY:校庆\r\n  放假\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261017\r\nSUMMARY:校庆补班\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	days, err := ParseCalendarICS(strings.NewReader(ics))
+	require.NoError(t, err)
+	require.Equal(t, []CalendarDay{
+		{Date: "2026-10-12", End: "2026-10-13", Type: DayTypeHoliday, Name: "校庆 放假"},
+		{Date: "2026-10-17", Type: DayTypeWorkday, Name: "校庆补班"},
+	}, days)
+
+	n, err := ImportCalendar("school", days, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	policy := DayPolicyCalendarPrefix + "school"
+	require.True(t, ValidDayPolicy(policy))
+	for date, want := range map[string]bool{"2026-10-12": false, "2026-10-13": false, "2026-10-14": true, "2026-10-17": true, "2026-10-18": false} {
+		day, err := time.Parse(calendarDateLayout, date)
+		require.NoError(t, err)
+		got, _, err := ShouldSignOn(policy, day)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, date)
+	}
+
+	// 导入的记录优先于内置日历
+	_, err = ImportCalendar(BuiltinCalendar, []CalendarDay{{Date: "2026-10-01", Type: DayTypeWorkday, Name: "值班"}}, false)
+	require.NoError(t, err)
+	workday, reason, err := IsWorkday(BuiltinCalendar, time.Date(2026, 10, 1, 8, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	assert.True(t, workday)
+	assert.Equal(t, "值班", reason)
+}
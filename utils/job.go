@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 定时任务的类型，每种类型由一个 JobHandler 执行，参数以 JSON 保存在 schedules.payload 中。
+const (
+	JobTypeSign          = "sign"           // 签到，参数为 SignPayload
+	JobTypeQuery         = "query"          // 查询签到信息并更新应签到坐标
+	JobTypeTokenRefresh  = "token_refresh"  // 使用保存的密码重新登录，刷新 token
+	JobTypeReport        = "report"         // 提交日报/周报/月报，参数为 ReportPayload
+	JobTypeSchoolRefresh = "school_refresh" // 重新获取学校列表，不需要账号
+	JobTypeSummary       = "summary"        // 发送执行日前一天所在月份的考勤汇总
+	JobTypeReportComment = "report_comment" // 查询报告的批阅，收到新的批阅时发送通知
+)
+
+// JobTypes 为支持的任务类型，顺序用于帮助信息。
+var JobTypes = []string{JobTypeSign, JobTypeQuery, JobTypeTokenRefresh, JobTypeReport, JobTypeSchoolRefresh, JobTypeSummary, JobTypeReportComment}
+
+// ValidJobType 判断是否为支持的任务类型
+func ValidJobType(jobType string) bool {
+	for _, t := range JobTypes {
+		if t == jobType {
+			return true
+		}
+	}
+	return false
+}
+
+// JobNeedsAccount 报告该类型的任务是否需要绑定账号
+func JobNeedsAccount(jobType string) bool {
+	return jobType != JobTypeSchoolRefresh
+}
+
+// SignPayload 是签到任务的参数，经纬度为空时使用数据库中保存的值。
+type SignPayload struct {
+	Address     string `json:"address,omitempty"`
+	AddressName string `json:"address_name,omitempty"`
+	Latitude    string `json:"latitude,omitempty"`
+	Longitude   string `json:"longitude,omitempty"`
+	Province    string `json:"province,omitempty"`
+	City        string `json:"city,omitempty"`
+	Remark      string `json:"remark,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// ReportPayload 是提交报告任务的参数。报告的起止日期按执行时间所在的日、周或月计算。
+type ReportPayload struct {
+	// BusinessType 为 day、week 或 month
+	BusinessType string `json:"business_type"`
+	// Content 为报告内容；ContentFile 不为空时在执行时读取该文件作为内容。
+	Content     string `json:"content,omitempty"`
+	ContentFile string `json:"content_file,omitempty"`
+	Attachment  string `json:"attachment,omitempty"`
+}
+
+// Job 是调度器交给 JobHandler 执行的一次任务。
+type Job struct {
+	ScheduleID int
+	Type       string
+	Account    string
+	Payload    string
+	// Trigger 为触发方式（JobTriggerSchedule 等），PlannedAt 为计划执行时间，手动执行时为零值。
+	Trigger   string
+	PlannedAt time.Time
+}
+
+// Decode 将任务参数解析到 v 中，参数为空时保持 v 不变。
+func (j Job) Decode(v interface{}) error {
+	if j.Payload == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(j.Payload), v); err != nil {
+		return fmt.Errorf("任务参数无效: %v", err)
+	}
+	return nil
+}
+
+// JobHandler 执行一种类型的任务。
+type JobHandler func(ctx context.Context, job Job) error
+
+// JobHandlers 按任务类型注册处理函数。
+type JobHandlers map[string]JobHandler
+
+// Run 使用对应类型的处理函数执行任务
+func (h JobHandlers) Run(ctx context.Context, job Job) error {
+	handler, ok := h[job.Type]
+	if !ok {
+		return fmt.Errorf("不支持的任务类型: %s", job.Type)
+	}
+	return handler(ctx, job)
+}
+
+// EncodePayload 将任务参数编码为 JSON，用于保存到 schedules.payload 或重试队列中。
+func EncodePayload(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("编码任务参数失败: %v", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"xixunyunsign/xixunyun"
+)
+
+// 任务执行的触发方式
+const (
+	JobTriggerSchedule = "schedule" // cron 按计划触发
+	JobTriggerCatchUp  = "catchup"  // 启动时补执行停机期间错过的任务
+	JobTriggerManual   = "manual"   // schedule run 手动执行
+	JobTriggerRetry    = "retry"    // 失败后从重试队列中重新执行
+)
+
+// 任务执行结果
+const (
+	JobOutcomeSuccess = "success"
+	JobOutcomeFailed  = "failed"
+	JobOutcomeSkipped = "skipped" // 按签到日期策略跳过，或错过执行后不补执行
+)
+
+// jobRunTimeLayout 是 job_runs 中时间的存储格式（UTC，精确到毫秒），可直接按字符串比较。
+const jobRunTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// JobRun 是调度器对一个定时任务的一次调用记录。
+type JobRun struct {
+	ID         int64
+	ScheduleID int
+	Account    string
+	Trigger    string
+	// PlannedAt 为计划执行时间，StartedAt 为实际开始时间，两者之差即调度延迟。
+	PlannedAt  time.Time
+	StartedAt  time.Time
+	Duration   time.Duration
+	Outcome    string
+	ErrorClass string
+	Error      string
+	RetryCount int
+}
+
+// Drift 返回实际开始时间相对计划时间的延迟
+func (r JobRun) Drift() time.Duration {
+	if r.PlannedAt.IsZero() {
+		return 0
+	}
+	return r.StartedAt.Sub(r.PlannedAt)
+}
+
+// JobRunFilter 是查询任务执行记录的过滤条件，零值字段不参与过滤。
+type JobRunFilter struct {
+	ScheduleID int
+	// From、To 为计划执行时间的范围，左闭右开。
+	From    time.Time
+	To      time.Time
+	Outcome string
+	Limit   int
+}
+
+// ErrorClass 返回错误的分类，用于统计失败原因：接口错误使用 xixunyun.ErrorKind，
+// 其余分为 http_<状态码>、timeout、canceled、network 和 error。
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	kind := xixunyun.KindOf(err)
+	if kind != "" && kind != xixunyun.KindUnknown {
+		return string(kind)
+	}
+	var httpErr *xixunyun.HTTPError
+	if errors.As(err, &httpErr) {
+		return fmt.Sprintf("http_%d", httpErr.StatusCode)
+	}
+	if kind == xixunyun.KindUnknown {
+		return string(kind)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+	return "error"
+}
+
+// RecordJobRun 保存一次任务执行记录，返回记录 ID。
+func RecordJobRun(r JobRun) (int64, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return 0, err
+		}
+	}
+	if r.PlannedAt.IsZero() {
+		r.PlannedAt = r.StartedAt
+	}
+	res, err := db.Exec(`
+    INSERT INTO job_runs (schedule_id, account, trigger_source, planned_at, started_at, duration_ms, outcome, error_class, error, retry_count)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ScheduleID, r.Account, r.Trigger, r.PlannedAt.UTC().Format(jobRunTimeLayout), r.StartedAt.UTC().Format(jobRunTimeLayout),
+		r.Duration.Milliseconds(), r.Outcome, r.ErrorClass, r.Error, r.RetryCount)
+	if err != nil {
+		return 0, fmt.Errorf("写入任务执行记录失败: %v", err)
+	}
+	return res.LastInsertId()
+}
+
+// QueryJobRuns 按条件查询任务执行记录，按计划时间倒序返回。
+func QueryJobRuns(f JobRunFilter) ([]JobRun, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	var where []string
+	var args []interface{}
+	if f.ScheduleID != 0 {
+		where = append(where, "schedule_id = ?")
+		args = append(args, f.ScheduleID)
+	}
+	if !f.From.IsZero() {
+		where = append(where, "planned_at >= ?")
+		args = append(args, f.From.UTC().Format(jobRunTimeLayout))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "planned_at < ?")
+		args = append(args, f.To.UTC().Format(jobRunTimeLayout))
+	}
+	if f.Outcome != "" {
+		where = append(where, "outcome = ?")
+		args = append(args, f.Outcome)
+	}
+
+	querySQL := `SELECT id, schedule_id, IFNULL(account, ''), IFNULL(trigger_source, ''), planned_at, started_at,
+        IFNULL(duration_ms, 0), outcome, IFNULL(error_class, ''), IFNULL(error, ''), IFNULL(retry_count, 0)
+        FROM job_runs`
+	if len(where) > 0 {
+		querySQL += " WHERE " + strings.Join(where, " AND ")
+	}
+	querySQL += " ORDER BY planned_at DESC, id DESC"
+	if f.Limit > 0 {
+		querySQL += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := db.Query(querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询任务执行记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	var runs []JobRun
+	for rows.Next() {
+		var (
+			r                  JobRun
+			plannedAt, startAt string
+			durationMs         int64
+		)
+		if err := rows.Scan(&r.ID, &r.ScheduleID, &r.Account, &r.Trigger, &plannedAt, &startAt,
+			&durationMs, &r.Outcome, &r.ErrorClass, &r.Error, &r.RetryCount); err != nil {
+			return nil, fmt.Errorf("读取任务执行记录失败: %v", err)
+		}
+		r.PlannedAt, _ = time.Parse(jobRunTimeLayout, plannedAt)
+		r.StartedAt, _ = time.Parse(jobRunTimeLayout, startAt)
+		r.Duration = time.Duration(durationMs) * time.Millisecond
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// MissingRuns 返回任务在 [from, to) 内按计划应执行、却没有任何执行记录的时间，
+// 用于发现守护进程未运行或任务静默丢失的情况。tolerance 为计划时间的允许误差。
+// 只检查任务第一条执行记录之后的时间；@every 这类按间隔执行的任务没有固定的计划时间，不做检查。
+func MissingRuns(t ScheduleTask, from, to time.Time, tolerance time.Duration) ([]time.Time, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+	sched, err := t.schedule()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := sched.(cron.ConstantDelaySchedule); ok {
+		return nil, nil
+	}
+
+	var first string
+	if err := db.QueryRow(`SELECT IFNULL(MIN(planned_at), '') FROM job_runs WHERE schedule_id = ?`, t.ID).Scan(&first); err != nil {
+		return nil, fmt.Errorf("查询任务执行记录失败: %v", err)
+	}
+	if first == "" {
+		return nil, nil
+	}
+	if firstAt, err := time.Parse(jobRunTimeLayout, first); err == nil && from.Before(firstAt.Add(-tolerance)) {
+		from = firstAt.Add(-tolerance)
+	}
+
+	runs, err := QueryJobRuns(JobRunFilter{ScheduleID: t.ID, From: from.Add(-tolerance), To: to.Add(tolerance)})
+	if err != nil {
+		return nil, err
+	}
+	var missing []time.Time
+	for next := sched.Next(from); !next.IsZero() && next.Before(to); next = sched.Next(next) {
+		found := false
+		for _, r := range runs {
+			if d := r.PlannedAt.Sub(next); d > -tolerance && d < tolerance {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, next)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xixunyunsign/xixunyun"
+)
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, "", ErrorClass(nil))
+	assert.Equal(t, "token_expired", ErrorClass(fmt.Errorf("签到失败: %w", xixunyun.ErrTokenExpired)))
+	assert.Equal(t, "http_502", ErrorClass(&xixunyun.HTTPError{StatusCode: 502}))
+	assert.Equal(t, "maintenance", ErrorClass(&xixunyun.HTTPError{StatusCode: 503}))
+	assert.Equal(t, "error", ErrorClass(errors.New("boom")))
+}
+
+func TestMissingRuns(t *testing.T) {
+	openTestDB(t)
+	task := ScheduleTask{ID: 1, CronExpr: "0 8 * * *"}
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 8, 0, 0, 0, time.UTC) }
+
+	for _, d := range []int{12, 14} {
+		_, err := RecordJobRun(JobRun{ScheduleID: 1, Trigger: JobTriggerSchedule, PlannedAt: day(d),
+			StartedAt: day(d).Add(300 * time.Millisecond), Outcome: JobOutcomeSuccess})
+		require.NoError(t, err)
+	}
+
+	runs, err := QueryJobRuns(JobRunFilter{ScheduleID: 1})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, day(14), runs[0].PlannedAt)
+	assert.Equal(t, 300*time.Millisecond, runs[0].Drift())
+
+	// 第一条执行记录之前的计划时间不算未执行
+	missing, err := MissingRuns(task, day(10), day(15).Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{day(13), day(15)}, missing)
+
+	// 没有执行记录的任务不做检查
+	missing, err = MissingRuns(ScheduleTask{ID: 2, CronExpr: "0 8 * * *"}, day(10), day(15), time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
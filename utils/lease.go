@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// SchedulerLease 是守护进程争用的租约名称，持有者为主节点，只有主节点执行定时任务。
+const SchedulerLease = "scheduler"
+
+// leaseTimeLayout 是 leases 与 job_claims 中时间的存储格式（UTC，精确到毫秒），可直接按字符串比较。
+const leaseTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// jobClaimRetention 是执行认领记录的保留时间
+const jobClaimRetention = 7 * 24 * time.Hour
+
+// Lease 描述租约的当前持有者
+type Lease struct {
+	Name       string
+	Holder     string
+	AcquiredAt time.Time
+	ExpiresAt  time.Time
+}
+
+// AcquireLease 尝试获取或续约租约：租约不存在、已过期或已由 holder 持有时，
+// 将其过期时间设为 now+ttl 并返回 true；由其他实例持有且未过期时返回 false。
+// 判断与写入在同一条语句中完成，多个进程同时争用时只有一个能成功。
+func AcquireLease(name, holder string, ttl time.Duration, now time.Time) (bool, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return false, err
+		}
+	}
+	nowStr := now.UTC().Format(leaseTimeLayout)
+	res, err := db.Exec(`INSERT INTO leases (name, holder, acquired_at, expires_at) VALUES (?, ?, ?, ?)
+    ON CONFLICT(name) DO UPDATE SET
+        acquired_at = CASE WHEN holder = excluded.holder THEN acquired_at ELSE excluded.acquired_at END,
+        holder = excluded.holder,
+        expires_at = excluded.expires_at
+    WHERE holder = excluded.holder OR expires_at < excluded.acquired_at`,
+		name, holder, nowStr, now.Add(ttl).UTC().Format(leaseTimeLayout))
+	if err != nil {
+		return false, fmt.Errorf("获取租约失败: %v", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseLease 释放 holder 持有的租约，便于其他实例立即接管
+func ReleaseLease(name, holder string) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+	if _, err := db.Exec(`DELETE FROM leases WHERE name = ? AND holder = ?`, name, holder); err != nil {
+		return fmt.Errorf("释放租约失败: %v", err)
+	}
+	return nil
+}
+
+// GetLease 返回租约的当前状态，租约不存在时返回零值
+func GetLease(name string) (Lease, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return Lease{}, err
+		}
+	}
+	l := Lease{Name: name}
+	var acquiredAt, expiresAt string
+	err := db.QueryRow(`SELECT holder, acquired_at, expires_at FROM leases WHERE name = ?`, name).Scan(&l.Holder, &acquiredAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Lease{Name: name}, nil
+	}
+	if err != nil {
+		return Lease{}, fmt.Errorf("查询租约失败: %v", err)
+	}
+	l.AcquiredAt, _ = time.Parse(leaseTimeLayout, acquiredAt)
+	l.ExpiresAt, _ = time.Parse(leaseTimeLayout, expiresAt)
+	return l, nil
+}
+
+// ClaimJobRun 认领定时任务在 planned 的一次执行，返回是否认领成功。
+// 同一次执行只有一个实例能认领成功，用于主节点切换前后避免重复执行。
+func ClaimJobRun(scheduleID int, planned time.Time, holder string, now time.Time) (bool, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return false, err
+		}
+	}
+	res, err := db.Exec(`INSERT OR IGNORE INTO job_claims (schedule_id, planned_at, holder, claimed_at) VALUES (?, ?, ?, ?)`,
+		scheduleID, planned.UTC().Format(leaseTimeLayout), holder, now.UTC().Format(leaseTimeLayout))
+	if err != nil {
+		return false, fmt.Errorf("认领任务执行失败: %v", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	// 顺便清理过期的认领记录，清理失败不影响本次认领
+	if _, err := db.Exec(`DELETE FROM job_claims WHERE claimed_at < ?`, now.Add(-jobClaimRetention).UTC().Format(leaseTimeLayout)); err != nil {
+		log.Printf("清理过期的任务认领记录失败: %v", err)
+	}
+	return true, nil
+}
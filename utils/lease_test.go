@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLease(t *testing.T) {
+	openTestDB(t)
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+
+	ok, err := AcquireLease(SchedulerLease, "a", time.Minute, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 未过期时其他实例无法获取，持有者可以续约
+	ok, err = AcquireLease(SchedulerLease, "b", time.Minute, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = AcquireLease(SchedulerLease, "a", time.Minute, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 持有者停止续约后由其他实例接管
+	ok, err = AcquireLease(SchedulerLease, "b", time.Minute, now.Add(91*time.Second))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	lease, err := GetLease(SchedulerLease)
+	require.NoError(t, err)
+	assert.Equal(t, "b", lease.Holder)
+	assert.Equal(t, now.Add(91*time.Second), lease.AcquiredAt)
+
+	require.NoError(t, ReleaseLease(SchedulerLease, "a"), "释放他人的租约不生效")
+	lease, err = GetLease(SchedulerLease)
+	require.NoError(t, err)
+	assert.Equal(t, "b", lease.Holder)
+	require.NoError(t, ReleaseLease(SchedulerLease, "b"))
+	lease, err = GetLease(SchedulerLease)
+	require.NoError(t, err)
+	assert.Empty(t, lease.Holder)
+
+	planned := now.Add(time.Hour)
+	claimed, err := ClaimJobRun(1, planned, "a", now)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = ClaimJobRun(1, planned, "b", now)
+	require.NoError(t, err)
+	assert.False(t, claimed, "同一次执行只能认领一次")
+}
+
+func TestSchedulerLeaseFailover(t *testing.T) {
+	openTestDB(t)
+	_, err := AddSchedule(ScheduleTask{Account: "user", CronExpr: "@every 1s", Enabled: 1})
+	require.NoError(t, err)
+
+	ran := make(chan string, 20)
+	handlers := func(holder string) JobHandlers {
+		return JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error {
+			ran <- holder
+			return nil
+		}}
+	}
+	ttl := 600 * time.Millisecond
+	a, err := InitScheduler(handlers("a"), WithLease("a", ttl))
+	require.NoError(t, err)
+	b, err := InitScheduler(handlers("b"), WithLease("b", ttl))
+	require.NoError(t, err)
+	defer func() { <-b.Stop().Done() }()
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+
+	select {
+	case holder := <-ran:
+		assert.Equal(t, "a", holder)
+	case <-time.After(3 * time.Second):
+		t.Fatal("主节点未执行任务")
+	}
+
+	// 主节点停止后另一个实例接管
+	<-a.Stop().Done()
+	require.Eventually(t, b.IsLeader, 3*time.Second, 50*time.Millisecond)
+	for {
+		select {
+		case holder := <-ran:
+			if holder == "b" {
+				return
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("新的主节点未执行任务")
+		}
+	}
+}
+
+func TestSchedulerFailoverUsesCurrentState(t *testing.T) {
+	openTestDB(t)
+	id, err := AddSchedule(ScheduleTask{Account: "user", CronExpr: "* * * * *", Enabled: 1, MissedPolicy: MissedPolicyNotify})
+	require.NoError(t, err)
+	require.NoError(t, RecordScheduleRun(id, time.Now().Add(-24*time.Hour), JobOutcomeSuccess))
+
+	handlers := JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error { return nil }}
+	ttl := 600 * time.Millisecond
+	a, err := InitScheduler(handlers, WithLease("a", ttl))
+	require.NoError(t, err)
+	notified := make(chan time.Time, 10)
+	b, err := InitScheduler(handlers, WithLease("b", ttl), WithCatchUp(time.Hour, func(t ScheduleTask, missedAt time.Time) {
+		notified <- missedAt
+	}))
+	require.NoError(t, err)
+	require.False(t, b.IsLeader())
+
+	// 原主节点执行后更新了 last_run_at，接管的实例不应按启动时读到的状态再次处理错过的执行
+	require.NoError(t, RecordScheduleRun(id, time.Now(), JobOutcomeSuccess))
+	<-a.Stop().Done()
+	require.Eventually(t, b.IsLeader, 3*time.Second, 50*time.Millisecond)
+	<-b.Stop().Done()
+	assert.Empty(t, notified)
+}
@@ -0,0 +1,426 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// migration 描述一次数据库结构升级。版本号必须连续递增，已发布的迁移不可修改，
+// 结构变更只能通过在 migrations 末尾追加新迁移完成。
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations 按版本号顺序排列的全部迁移。
+var migrations = []migration{
+	{1, "创建 users、school_info、schedules 表", migrateInitialTables},
+	{2, "school_info 表增加 city_name、city_id 列", migrateSchoolInfoCity},
+	{3, "创建 app_meta 表", migrateAppMeta},
+	{4, "创建 sign_logs 签到记录表", migrateSignLogs},
+	{5, "schedules 表增删任务或修改任务配置时递增 schedules_rev", migrateSchedulesRevision},
+	{6, "schedules 表增加 last_run_at、last_result、missed_policy、created_at 列", migrateScheduleRunState},
+	{7, "创建 holidays 节假日表，schedules 表增加 day_policy 列", migrateHolidays},
+	{8, "schedules 表增加 timezone 列", migrateScheduleTimezone},
+	{9, "创建 job_runs 任务执行记录表", migrateJobRuns},
+	{10, "创建 retry_queue 重试队列表", migrateRetryQueue},
+	{11, "schedules 表增加 job_type、payload 列", migrateScheduleJobs},
+	{12, "schedules 表增加 run_at、completed_at 列", migrateScheduleRunAt},
+	{13, "创建 leases 租约表与 job_claims 任务认领表", migrateLeases},
+	{14, "创建 notify_channels 通知渠道表", migrateNotifyChannels},
+	{15, "notify_channels 表增加 schedule_id 列", migrateNotifyChannelSchedule},
+}
+
+// AutoMigrate 为 true 时 InitDB 会自动执行未应用的迁移。
+// 可通过环境变量 XIXUN_AUTO_MIGRATE=0 关闭，之后使用 `xixun db migrate` 手动升级。
+var AutoMigrate = os.Getenv("XIXUN_AUTO_MIGRATE") != "0"
+
+// MigrationStatus 描述一次迁移的应用状态。
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+}
+
+// LatestSchemaVersion 返回程序支持的最新数据库版本。
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func ensureSchemaVersionTable() error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+        version INTEGER PRIMARY KEY,
+        name TEXT,
+        applied_at TEXT
+    )`)
+	if err != nil {
+		return fmt.Errorf("创建 schema_version 表失败: %v", err)
+	}
+	return nil
+}
+
+// SchemaVersion 返回数据库当前的结构版本，尚未执行过迁移时为 0。
+func SchemaVersion() (int, error) {
+	if err := ensureSchemaVersionTable(); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT IFNULL(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("查询数据库版本失败: %v", err)
+	}
+	return version, nil
+}
+
+// Migrate 按顺序执行所有未应用的迁移，返回本次应用的迁移版本号。
+func Migrate() ([]int, error) {
+	current, err := SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > LatestSchemaVersion() {
+		return nil, fmt.Errorf("数据库版本(%d)高于程序支持的版本(%d)，请升级程序", current, LatestSchemaVersion())
+	}
+
+	var applied []int
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		err := withTx(func(tx *sql.Tx) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.version, m.name, time.Now().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("执行迁移 %d(%s) 失败: %v", m.version, m.name, err)
+		}
+		if !Stateless {
+			// 内存数据库每次启动都从头迁移，不必提示
+			log.Printf("数据库已升级到版本 %d: %s", m.version, m.name)
+		}
+		applied = append(applied, m.version)
+	}
+	return applied, nil
+}
+
+// MigrationStatuses 返回所有迁移及其应用状态。
+func MigrationStatuses() ([]MigrationStatus, error) {
+	if err := ensureSchemaVersionTable(); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT version, IFNULL(applied_at, '') FROM schema_version`)
+	if err != nil {
+		return nil, fmt.Errorf("查询迁移记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]string{}
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("读取迁移记录失败: %v", err)
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		at, ok := appliedAt[m.version]
+		statuses = append(statuses, MigrationStatus{Version: m.version, Name: m.name, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// columnExists 判断表中是否已存在指定列。
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// addColumn 在列不存在时为表增加一列，用于兼容在迁移机制引入前手动改过结构的数据库。
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+func migrateInitialTables(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+    CREATE TABLE IF NOT EXISTS users (
+        account TEXT PRIMARY KEY,
+        password TEXT,
+        token TEXT,
+        latitude TEXT,
+        longitude TEXT,
+        bind_phone TEXT,
+        user_number TEXT,
+        user_name TEXT,
+        school_id INT,
+        sex TEXT,
+        class_name TEXT,
+        entrance_year TEXT,
+        graduation_year TEXT
+    );
+    CREATE TABLE IF NOT EXISTS school_info (
+        school_id TEXT PRIMARY KEY,
+        school_name TEXT
+    );
+    CREATE TABLE IF NOT EXISTS schedules (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        account TEXT,
+        address TEXT,
+        latitude TEXT,
+        longitude TEXT,
+        province TEXT,
+        city TEXT,
+        remark TEXT,
+        comment TEXT,
+        cron_expr TEXT,
+        enabled INTEGER DEFAULT 1
+    );`)
+	return err
+}
+
+func migrateSchoolInfoCity(tx *sql.Tx) error {
+	if err := addColumn(tx, "school_info", "city_name", "TEXT"); err != nil {
+		return err
+	}
+	return addColumn(tx, "school_info", "city_id", "TEXT")
+}
+
+func migrateAppMeta(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS app_meta (
+        key TEXT PRIMARY KEY,
+        value TEXT
+    )`)
+	return err
+}
+
+func migrateSignLogs(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+    CREATE TABLE IF NOT EXISTS sign_logs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        account TEXT NOT NULL,
+        signed_at TEXT NOT NULL,
+        latitude TEXT,
+        longitude TEXT,
+        address TEXT,
+        status TEXT NOT NULL,
+        code INTEGER,
+        message TEXT,
+        error_kind TEXT,
+        duration_ms INTEGER,
+        trigger_source TEXT
+    );
+    CREATE INDEX IF NOT EXISTS idx_sign_logs_account_time ON sign_logs (account, signed_at);`)
+	return err
+}
+
+// migrateSchedulesRevision 通过触发器在 schedules 表增删任务或修改任务配置（包括手动编辑数据库）时
+// 递增 app_meta 中的 schedules_rev，守护进程据此判断是否需要重新加载定时任务。
+// UPDATE 触发器只关注任务配置的列，记录执行结果(last_run_at 等)不会触发重新加载；
+// 其中部分列由之后的迁移添加，SQLite 允许 UPDATE OF 引用尚不存在的列，列添加后即生效。
+func migrateSchedulesRevision(tx *sql.Tx) error {
+	triggers := map[string]string{
+		"insert": "INSERT",
+		"update": `UPDATE OF account, address, latitude, longitude, province, city, remark, comment, cron_expr, enabled,
+        missed_policy, day_policy, timezone, job_type, payload, run_at`,
+		"delete": "DELETE",
+	}
+	for _, name := range []string{"insert", "update", "delete"} {
+		_, err := tx.Exec(fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS schedules_rev_%s AFTER %s ON schedules
+    BEGIN
+        INSERT INTO app_meta (key, value) VALUES ('%s', 1)
+            ON CONFLICT(key) DO UPDATE SET value = CAST(value AS INTEGER) + 1;
+    END`, name, triggers[name], metaSchedulesRevision))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateScheduleRunState 记录任务最近一次执行的时间与结果、错过执行时的策略，以及任务的创建时间。
+// 创建时间是从未执行过的任务判断是否错过执行的起点，已有任务的创建时间未知，使用升级的时间。
+func migrateScheduleRunState(tx *sql.Tx) error {
+	for _, c := range [][2]string{
+		{"last_run_at", "TEXT"},
+		{"last_result", "TEXT"},
+		{"missed_policy", "TEXT DEFAULT 'skip'"},
+		{"created_at", "TEXT"},
+	} {
+		if err := addColumn(tx, "schedules", c[0], c[1]); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(`UPDATE schedules SET created_at = ? WHERE created_at IS NULL`, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func migrateHolidays(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+    CREATE TABLE IF NOT EXISTS holidays (
+        calendar TEXT NOT NULL,
+        date TEXT NOT NULL,
+        type TEXT NOT NULL,
+        name TEXT,
+        PRIMARY KEY (calendar, date)
+    )`)
+	if err != nil {
+		return err
+	}
+	return addColumn(tx, "schedules", "day_policy", "TEXT DEFAULT 'everyday'")
+}
+
+func migrateScheduleTimezone(tx *sql.Tx) error {
+	return addColumn(tx, "schedules", "timezone", "TEXT DEFAULT ''")
+}
+
+func migrateJobRuns(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+    CREATE TABLE IF NOT EXISTS job_runs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        schedule_id INTEGER NOT NULL,
+        account TEXT,
+        trigger_source TEXT,
+        planned_at TEXT NOT NULL,
+        started_at TEXT NOT NULL,
+        duration_ms INTEGER,
+        outcome TEXT NOT NULL,
+        error_class TEXT,
+        error TEXT,
+        retry_count INTEGER DEFAULT 0
+    );
+    CREATE INDEX IF NOT EXISTS idx_job_runs_schedule_planned ON job_runs (schedule_id, planned_at);`)
+	return err
+}
+
+func migrateRetryQueue(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+    CREATE TABLE IF NOT EXISTS retry_queue (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        kind TEXT NOT NULL,
+        account TEXT NOT NULL,
+        schedule_id INTEGER DEFAULT 0,
+        payload TEXT,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        max_attempts INTEGER,
+        next_attempt_at TEXT NOT NULL,
+        deadline TEXT,
+        last_error TEXT,
+        error_class TEXT,
+        status TEXT NOT NULL,
+        created_at TEXT NOT NULL,
+        updated_at TEXT
+    );
+    CREATE INDEX IF NOT EXISTS idx_retry_queue_status_next ON retry_queue (status, next_attempt_at);`)
+	return err
+}
+
+// migrateScheduleJobs 为定时任务增加任务类型与 JSON 参数。已有的任务均为签到任务，
+// 其地址、经纬度等列转存到 payload 中，原有的列保留但不再使用。
+func migrateScheduleJobs(tx *sql.Tx) error {
+	if err := addColumn(tx, "schedules", "job_type", "TEXT DEFAULT 'sign'"); err != nil {
+		return err
+	}
+	if err := addColumn(tx, "schedules", "payload", "TEXT DEFAULT '{}'"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE schedules SET payload = json_object(
+        'address', IFNULL(address, ''), 'latitude', IFNULL(latitude, ''), 'longitude', IFNULL(longitude, ''),
+        'province', IFNULL(province, ''), 'city', IFNULL(city, ''), 'remark', IFNULL(remark, ''), 'comment', IFNULL(comment, ''))
+        WHERE job_type = 'sign'`)
+	return err
+}
+
+// migrateScheduleRunAt 支持只在指定时间执行一次的任务：run_at 不为空的任务不使用 cron 表达式，
+// 执行后写入 completed_at，不再加载。
+func migrateScheduleRunAt(tx *sql.Tx) error {
+	if err := addColumn(tx, "schedules", "run_at", "TEXT"); err != nil {
+		return err
+	}
+	return addColumn(tx, "schedules", "completed_at", "TEXT")
+}
+
+// migrateLeases 创建多个守护进程共用数据库时选举主节点的租约表，以及保证同一次执行只被认领一次的 job_claims 表。
+func migrateLeases(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+    CREATE TABLE IF NOT EXISTS leases (
+        name TEXT PRIMARY KEY,
+        holder TEXT NOT NULL,
+        acquired_at TEXT NOT NULL,
+        expires_at TEXT NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS job_claims (
+        schedule_id INTEGER NOT NULL,
+        planned_at TEXT NOT NULL,
+        holder TEXT NOT NULL,
+        claimed_at TEXT NOT NULL,
+        PRIMARY KEY (schedule_id, planned_at)
+    );
+    CREATE INDEX IF NOT EXISTS idx_job_claims_claimed ON job_claims (claimed_at);`)
+	return err
+}
+
+// migrateNotifyChannels 创建通知渠道表。account 为空的渠道对所有账号生效，config 为 JSON 格式的渠道参数。
+func migrateNotifyChannels(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+    CREATE TABLE IF NOT EXISTS notify_channels (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        account TEXT NOT NULL DEFAULT '',
+        kind TEXT NOT NULL,
+        config TEXT NOT NULL DEFAULT '{}',
+        enabled INTEGER NOT NULL DEFAULT 1,
+        created_at TEXT NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_notify_channels_account ON notify_channels (account);`)
+	return err
+}
+
+// migrateNotifyChannelSchedule 支持只用于某个定时任务的通知渠道，定时任务删除时一并删除。
+func migrateNotifyChannelSchedule(tx *sql.Tx) error {
+	if err := addColumn(tx, "notify_channels", "schedule_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`CREATE TRIGGER IF NOT EXISTS notify_channels_schedule_delete AFTER DELETE ON schedules
+    BEGIN
+        DELETE FROM notify_channels WHERE schedule_id = OLD.id;
+    END`)
+	return err
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	DBPath = filepath.Join(t.TempDir(), "config.db")
+
+	// 构造迁移机制引入前、school_info 还没有城市列的旧数据库
+	legacy, err := sql.Open("sqlite3", DBPath)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`CREATE TABLE school_info (school_id TEXT PRIMARY KEY, school_name TEXT);
+        INSERT INTO school_info (school_id, school_name) VALUES ('7', '示例学院');
+        CREATE TABLE schedules (id INTEGER PRIMARY KEY AUTOINCREMENT, account TEXT, address TEXT, latitude TEXT, longitude TEXT,
+            province TEXT, city TEXT, remark TEXT, comment TEXT, cron_expr TEXT, enabled INTEGER DEFAULT 1);
+        INSERT INTO schedules (account, address, latitude, longitude, remark, cron_expr) VALUES ('user', '江苏省南京市', '32.05', '118.79', '0', '30 8 * * *');`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db = nil
+	require.NoError(t, InitDB())
+	t.Cleanup(func() {
+		CloseDB()
+		db = nil
+	})
+
+	version, err := SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), version)
+
+	// 旧数据保留，新列可用
+	require.NoError(t, SaveSchoolInfo("南京", "320100", "8", "另一所学院"))
+	schools, err := SearchSchoolID("学院")
+	require.NoError(t, err)
+	assert.Len(t, schools, 2)
+
+	// 旧的定时任务转换为签到任务，地址、经纬度转存到 payload 中
+	task, err := GetSchedule(1)
+	require.NoError(t, err)
+	assert.Equal(t, JobTypeSign, task.JobType)
+	var p SignPayload
+	require.NoError(t, task.Job(JobTriggerManual, time.Time{}).Decode(&p))
+	assert.Equal(t, SignPayload{Address: "江苏省南京市", Latitude: "32.05", Longitude: "118.79", Remark: "0"}, p)
+
+	// 再次执行不会重复应用
+	applied, err := Migrate()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := MigrationStatuses()
+	require.NoError(t, err)
+	for _, st := range statuses {
+		assert.True(t, st.Applied, st.Name)
+	}
+}
+
+func TestMigrateRejectsNewerDatabase(t *testing.T) {
+	openTestDB(t)
+	_, err := db.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, 'future', '')`, LatestSchemaVersion()+1)
+	require.NoError(t, err)
+
+	_, err = Migrate()
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// NotifyChannel 是一个通知渠道配置。Account 为空时对所有账号生效，ScheduleID 不为 0 时只用于该定时任务；
+// Config 为渠道参数(如 Server酱的 sendkey)，开启静态加密时加密保存。
+type NotifyChannel struct {
+	ID         int64
+	Account    string
+	ScheduleID int
+	Kind       string
+	Config     map[string]string
+	Enabled    bool
+	CreatedAt  time.Time
+}
+
+// AddNotifyChannel 保存一个通知渠道，返回其 id
+func AddNotifyChannel(c NotifyChannel) (int64, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return 0, err
+		}
+	}
+	data, err := json.Marshal(c.Config)
+	if err != nil {
+		return 0, err
+	}
+	config, err := sealSecret(string(data))
+	if err != nil {
+		return 0, err
+	}
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	res, err := db.Exec(`INSERT INTO notify_channels (account, schedule_id, kind, config, enabled, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		c.Account, c.ScheduleID, c.Kind, config, c.Enabled, c.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("保存通知渠道失败: %v", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListNotifyChannels 返回账号的通知渠道，account 为空时返回全部
+func ListNotifyChannels(account string) ([]NotifyChannel, error) {
+	if account == "" {
+		return queryNotifyChannels(`1 = 1`)
+	}
+	return queryNotifyChannels(`account = ?`, account)
+}
+
+// NotifyChannelsFor 返回发送账号通知时使用的已启用渠道，包括对所有账号生效的渠道；
+// scheduleID 不为 0 时还包括该定时任务的渠道。
+func NotifyChannelsFor(account string, scheduleID int) ([]NotifyChannel, error) {
+	return queryNotifyChannels(`enabled = 1 AND (account = ? OR account = '') AND (schedule_id = 0 OR schedule_id = ?)`, account, scheduleID)
+}
+
+// DeleteNotifyChannel 删除通知渠道
+func DeleteNotifyChannel(id int64) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+	res, err := db.Exec(`DELETE FROM notify_channels WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("删除通知渠道失败: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("通知渠道 %d 不存在", id)
+	}
+	return nil
+}
+
+func queryNotifyChannels(where string, args ...interface{}) ([]NotifyChannel, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := db.Query(`SELECT id, account, schedule_id, kind, config, enabled, created_at FROM notify_channels WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询通知渠道失败: %v", err)
+	}
+	defer rows.Close()
+
+	var channels []NotifyChannel
+	for rows.Next() {
+		var c NotifyChannel
+		var config, createdAt string
+		if err := rows.Scan(&c.ID, &c.Account, &c.ScheduleID, &c.Kind, &config, &c.Enabled, &createdAt); err != nil {
+			return nil, err
+		}
+		plain, err := openSecret(config)
+		if err != nil {
+			return nil, fmt.Errorf("读取通知渠道 %d 失败: %w", c.ID, err)
+		}
+		if err := json.Unmarshal([]byte(plain), &c.Config); err != nil {
+			return nil, fmt.Errorf("解析通知渠道 %d 的参数失败: %v", c.ID, err)
+		}
+		c.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		channels = append(channels, c)
+	}
+	return channels, rows.Err()
+}
+
+// rewriteNotifySecrets 在事务中使用 fn 转换 notify_channels 表中所有的渠道参数。
+func rewriteNotifySecrets(tx *sql.Tx, fn func(string) (string, error)) error {
+	rows, err := tx.Query(`SELECT id, config FROM notify_channels`)
+	if err != nil {
+		return fmt.Errorf("读取通知渠道失败: %w", err)
+	}
+	type configRow struct {
+		id     int64
+		config string
+	}
+	var all []configRow
+	for rows.Next() {
+		var r configRow
+		if err := rows.Scan(&r.id, &r.config); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取通知渠道失败: %w", err)
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range all {
+		config, err := fn(r.config)
+		if err != nil {
+			return fmt.Errorf("处理通知渠道 %d 的参数失败: %w", r.id, err)
+		}
+		if _, err := tx.Exec(`UPDATE notify_channels SET config = ? WHERE id = ?`, config, r.id); err != nil {
+			return fmt.Errorf("更新通知渠道 %d 失败: %w", r.id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyChannels(t *testing.T) {
+	openTestDB(t)
+	t.Setenv(EnvMasterKey, "")
+
+	global, err := AddNotifyChannel(NotifyChannel{Kind: "serverchan", Config: map[string]string{"sendkey": "SCT1"}, Enabled: true})
+	require.NoError(t, err)
+	_, err = AddNotifyChannel(NotifyChannel{Account: "user", Kind: "serverchan", Config: map[string]string{"sendkey": "SCT2"}, Enabled: true})
+	require.NoError(t, err)
+	_, err = AddNotifyChannel(NotifyChannel{Account: "other", Kind: "serverchan", Config: map[string]string{"sendkey": "SCT3"}, Enabled: true})
+	require.NoError(t, err)
+	_, err = AddNotifyChannel(NotifyChannel{Account: "user", Kind: "serverchan", Config: map[string]string{"sendkey": "SCT4"}})
+	require.NoError(t, err)
+
+	// 账号的渠道包括对所有账号生效的渠道，不包括停用的渠道
+	channels, err := NotifyChannelsFor("user", 0)
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+	assert.Equal(t, "SCT1", channels[0].Config["sendkey"])
+	assert.Equal(t, "SCT2", channels[1].Config["sendkey"])
+
+	all, err := ListNotifyChannels("")
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+
+	// 开启静态加密后渠道参数同样加密保存
+	require.NoError(t, EncryptDatabase("master"))
+	var stored string
+	require.NoError(t, db.QueryRow(`SELECT config FROM notify_channels WHERE id = ?`, global).Scan(&stored))
+	assert.True(t, IsEncrypted(stored))
+	_, err = NotifyChannelsFor("user", 0)
+	assert.ErrorIs(t, err, ErrMasterKeyRequired)
+
+	t.Setenv(EnvMasterKey, "master")
+	channels, err = NotifyChannelsFor("other", 0)
+	require.NoError(t, err)
+	require.Len(t, channels, 2)
+	assert.Equal(t, "SCT3", channels[1].Config["sendkey"])
+
+	require.NoError(t, DeleteNotifyChannel(global))
+	assert.Error(t, DeleteNotifyChannel(global))
+	channels, err = NotifyChannelsFor("other", 0)
+	require.NoError(t, err)
+	assert.Len(t, channels, 1)
+}
+
+func TestScheduleNotifyChannels(t *testing.T) {
+	openTestDB(t)
+	id, err := AddSchedule(ScheduleTask{Account: "user", CronExpr: "30 8 * * *", Enabled: 1})
+	require.NoError(t, err)
+	_, err = AddNotifyChannel(NotifyChannel{Account: "user", Kind: "serverchan", Config: map[string]string{"sendkey": "SCT1"}, Enabled: true})
+	require.NoError(t, err)
+	_, err = AddNotifyChannel(NotifyChannel{Account: "user", ScheduleID: id, Kind: "serverchan", Config: map[string]string{"sendkey": "SCT2"}, Enabled: true})
+	require.NoError(t, err)
+
+	// 定时任务的渠道只用于该任务
+	channels, err := NotifyChannelsFor("user", 0)
+	require.NoError(t, err)
+	assert.Len(t, channels, 1)
+	channels, err = NotifyChannelsFor("user", id)
+	require.NoError(t, err)
+	assert.Len(t, channels, 2)
+
+	// 删除定时任务时一并删除其渠道
+	require.NoError(t, DeleteSchedule(id))
+	all, err := ListNotifyChannels("")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, 0, all[0].ScheduleID)
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// metaReportCommentsPrefix 是 app_meta 中记录账号已处理的报告批阅的键前缀，后接账号。
+const metaReportCommentsPrefix = "report_comments:"
+
+// SeenReportComments 返回账号已处理过的报告批阅(报告 ID → 批阅内容)。
+// 从未查询过批阅时 ok 为 false，调用方据此只记录现有批阅而不发送通知。
+func SeenReportComments(account string) (seen map[string]string, ok bool, err error) {
+	value, err := getMeta(metaReportCommentsPrefix + account)
+	if err != nil || value == "" {
+		return map[string]string{}, false, err
+	}
+	if err := json.Unmarshal([]byte(value), &seen); err != nil {
+		return nil, false, err
+	}
+	return seen, true, nil
+}
+
+// SaveSeenReportComments 保存账号已处理过的报告批阅。
+func SaveSeenReportComments(account string, seen map[string]string) error {
+	data, err := json.Marshal(seen)
+	if err != nil {
+		return err
+	}
+	return withTx(func(tx *sql.Tx) error {
+		return setMetaTx(tx, metaReportCommentsPrefix+account, string(data))
+	})
+}
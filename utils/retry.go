@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 重试任务的状态
+const (
+	RetryStatusPending   = "pending"   // 等待重试
+	RetryStatusSucceeded = "succeeded" // 重试成功
+	RetryStatusExhausted = "exhausted" // 达到最大重试次数
+	RetryStatusExpired   = "expired"   // 超过截止时间
+	RetryStatusFailed    = "failed"    // 遇到不可重试的错误
+)
+
+// 重试间隔从 RetryBaseDelay 开始每次翻倍，最长为 RetryMaxDelay。
+var (
+	RetryBaseDelay = time.Minute
+	RetryMaxDelay  = time.Hour
+)
+
+// retryTimeLayout 是 retry_queue 中时间的存储格式（UTC），可直接按字符串比较。
+const retryTimeLayout = "2006-01-02T15:04:05Z"
+
+// RetryItem 是重试队列中的一项。Kind 为任务类型（JobTypeSign 等），Payload 为该类型的 JSON 参数，
+// 重试时交给同一类型的 JobHandler 执行。
+type RetryItem struct {
+	ID            int64
+	Kind          string
+	Account       string
+	ScheduleID    int
+	Payload       string
+	Attempts      int
+	MaxAttempts   int
+	NextAttemptAt time.Time
+	Deadline      time.Time
+	LastError     string
+	ErrorClass    string
+	Status        string
+	CreatedAt     time.Time
+}
+
+// IsRetryable 判断错误是否为临时性错误（网络错误、超时、5xx、维护中、限流），值得稍后重试。
+func IsRetryable(err error) bool {
+	switch class := ErrorClass(err); class {
+	case "network", "timeout", "maintenance", "rate_limited":
+		return true
+	default:
+		return strings.HasPrefix(class, "http_5")
+	}
+}
+
+// RetryBackoff 返回第 attempts 次失败后到下次重试的等待时间
+func RetryBackoff(attempts int) time.Duration {
+	delay := RetryBaseDelay
+	for i := 1; i < attempts && delay < RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > RetryMaxDelay {
+		delay = RetryMaxDelay
+	}
+	return delay
+}
+
+// EnqueueRetry 将一次失败的尝试加入重试队列，第一次重试在 RetryBackoff(1) 之后。
+// 同一账号已有同类型的待重试任务时不重复加入，返回已有任务。
+// 已达到最大尝试次数或第一次重试就晚于截止时间时直接以 exhausted、expired 状态记录，
+// 调用方应据此发送放弃重试的通知。
+func EnqueueRetry(item RetryItem, now time.Time) (RetryItem, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return item, err
+		}
+	}
+	if existing, err := pendingRetry(item.Kind, item.Account); err != nil || existing.ID != 0 {
+		return existing, err
+	}
+
+	item.Status = RetryStatusPending
+	item.CreatedAt = now
+	if item.Attempts == 0 {
+		item.Attempts = 1
+	}
+	item.NextAttemptAt = now.Add(RetryBackoff(item.Attempts))
+	switch {
+	case item.MaxAttempts > 0 && item.Attempts >= item.MaxAttempts:
+		item.Status = RetryStatusExhausted
+	case !item.Deadline.IsZero() && item.NextAttemptAt.After(item.Deadline):
+		item.Status = RetryStatusExpired
+	}
+	res, err := db.Exec(`
+    INSERT INTO retry_queue (kind, account, schedule_id, payload, attempts, max_attempts, next_attempt_at, deadline,
+        last_error, error_class, status, created_at, updated_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.Kind, item.Account, item.ScheduleID, item.Payload, item.Attempts, item.MaxAttempts,
+		formatRetryTime(item.NextAttemptAt), formatRetryTime(item.Deadline), item.LastError, item.ErrorClass,
+		item.Status, formatRetryTime(now), formatRetryTime(now))
+	if err != nil {
+		return item, fmt.Errorf("加入重试队列失败: %v", err)
+	}
+	item.ID, err = res.LastInsertId()
+	return item, err
+}
+
+// pendingRetry 返回账号同类型的待重试任务，不存在时返回零值。
+func pendingRetry(kind, account string) (RetryItem, error) {
+	items, err := queryRetries(`kind = ? AND account = ? AND status = ?`, "id", kind, account, RetryStatusPending)
+	if err != nil || len(items) == 0 {
+		return RetryItem{}, err
+	}
+	return items[0], nil
+}
+
+// DueRetries 返回到达重试时间的待重试任务
+func DueRetries(now time.Time) ([]RetryItem, error) {
+	return queryRetries(`status = ? AND next_attempt_at <= ?`, "next_attempt_at, id", RetryStatusPending, formatRetryTime(now))
+}
+
+// ListRetries 返回指定状态（为空时返回全部）的重试任务，最近创建的在前
+func ListRetries(status string, limit int) ([]RetryItem, error) {
+	where, args := "", []interface{}{}
+	if status != "" {
+		where, args = "status = ?", append(args, status)
+	}
+	order := "id DESC"
+	if limit > 0 {
+		order += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return queryRetries(where, order, args...)
+}
+
+// MarkRetrySucceeded 标记重试成功
+func MarkRetrySucceeded(id int64, now time.Time) error {
+	return updateRetry(id, `status = ?, updated_at = ?`, RetryStatusSucceeded, formatRetryTime(now))
+}
+
+// MarkRetryFailed 记录一次失败的重试并计算下次重试时间，返回更新后的任务。
+// 错误不可重试、达到最大次数或下次重试时间超过截止时间时，任务不再重试，Status 为对应的终止状态。
+func MarkRetryFailed(item RetryItem, cause error, now time.Time) (RetryItem, error) {
+	item.Attempts++
+	item.LastError = cause.Error()
+	item.ErrorClass = ErrorClass(cause)
+	item.NextAttemptAt = now.Add(RetryBackoff(item.Attempts))
+	switch {
+	case !IsRetryable(cause):
+		item.Status = RetryStatusFailed
+	case item.MaxAttempts > 0 && item.Attempts >= item.MaxAttempts:
+		item.Status = RetryStatusExhausted
+	case !item.Deadline.IsZero() && item.NextAttemptAt.After(item.Deadline):
+		item.Status = RetryStatusExpired
+	default:
+		item.Status = RetryStatusPending
+	}
+	err := updateRetry(item.ID, `attempts = ?, last_error = ?, error_class = ?, next_attempt_at = ?, status = ?, updated_at = ?`,
+		item.Attempts, item.LastError, item.ErrorClass, formatRetryTime(item.NextAttemptAt), item.Status, formatRetryTime(now))
+	return item, err
+}
+
+// ExpireRetries 将已超过截止时间仍未成功的任务标记为过期，返回被标记的任务
+func ExpireRetries(now time.Time) ([]RetryItem, error) {
+	items, err := queryRetries(`status = ? AND deadline != '' AND deadline < ?`, "id", RetryStatusPending, formatRetryTime(now))
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Status = RetryStatusExpired
+		if err := updateRetry(items[i].ID, `status = ?, updated_at = ?`, RetryStatusExpired, formatRetryTime(now)); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func updateRetry(id int64, set string, args ...interface{}) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+	args = append(args, id)
+	if _, err := db.Exec(`UPDATE retry_queue SET `+set+` WHERE id = ?`, args...); err != nil {
+		return fmt.Errorf("更新重试队列失败: %v", err)
+	}
+	return nil
+}
+
+func queryRetries(where, order string, args ...interface{}) ([]RetryItem, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+	querySQL := `SELECT id, kind, account, IFNULL(schedule_id, 0), IFNULL(payload, ''), attempts, IFNULL(max_attempts, 0),
+        next_attempt_at, IFNULL(deadline, ''), IFNULL(last_error, ''), IFNULL(error_class, ''), status, created_at
+        FROM retry_queue`
+	if where != "" {
+		querySQL += " WHERE " + where
+	}
+	querySQL += " ORDER BY " + order
+
+	rows, err := db.Query(querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询重试队列失败: %v", err)
+	}
+	defer rows.Close()
+
+	var items []RetryItem
+	for rows.Next() {
+		var (
+			item                        RetryItem
+			nextAt, deadline, createdAt string
+		)
+		if err := rows.Scan(&item.ID, &item.Kind, &item.Account, &item.ScheduleID, &item.Payload, &item.Attempts, &item.MaxAttempts,
+			&nextAt, &deadline, &item.LastError, &item.ErrorClass, &item.Status, &createdAt); err != nil {
+			return nil, fmt.Errorf("读取重试队列失败: %v", err)
+		}
+		item.NextAttemptAt = parseRetryTime(nextAt)
+		item.Deadline = parseRetryTime(deadline)
+		item.CreatedAt = parseRetryTime(createdAt)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func formatRetryTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(retryTimeLayout)
+}
+
+func parseRetryTime(s string) time.Time {
+	t, _ := time.Parse(retryTimeLayout, s)
+	return t
+}
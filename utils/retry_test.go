@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"xixunyunsign/xixunyun"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, RetryBackoff(1))
+	assert.Equal(t, 2*time.Minute, RetryBackoff(2))
+	assert.Equal(t, 8*time.Minute, RetryBackoff(4))
+	assert.Equal(t, time.Hour, RetryBackoff(20))
+
+	assert.True(t, IsRetryable(&xixunyun.HTTPError{StatusCode: 502}))
+	assert.True(t, IsRetryable(&xixunyun.HTTPError{StatusCode: 503}))
+	assert.False(t, IsRetryable(xixunyun.ErrTokenExpired))
+	assert.False(t, IsRetryable(errors.New("boom")))
+}
+
+func TestRetryQueue(t *testing.T) {
+	openTestDB(t)
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	transient := &xixunyun.HTTPError{StatusCode: 502}
+
+	item, err := EnqueueRetry(RetryItem{Kind: JobTypeSign, Account: "alice", Payload: `{}`, MaxAttempts: 3,
+		Deadline: now.Add(time.Hour)}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, item.Attempts)
+	assert.Equal(t, now.Add(time.Minute), item.NextAttemptAt)
+
+	// 同一账号已有待重试任务时不重复加入
+	dup, err := EnqueueRetry(RetryItem{Kind: JobTypeSign, Account: "alice", Deadline: now.Add(time.Hour)}, now)
+	require.NoError(t, err)
+	assert.Equal(t, item.ID, dup.ID)
+
+	due, err := DueRetries(now)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	due, err = DueRetries(now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+
+	item, err = MarkRetryFailed(due[0], transient, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, RetryStatusPending, item.Status)
+	assert.Equal(t, now.Add(3*time.Minute), item.NextAttemptAt)
+
+	item, err = MarkRetryFailed(item, transient, now.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, RetryStatusExhausted, item.Status)
+
+	// 不可重试的错误直接终止
+	other, err := EnqueueRetry(RetryItem{Kind: JobTypeSign, Account: "bob", Deadline: now.Add(time.Hour)}, now)
+	require.NoError(t, err)
+	other, err = MarkRetryFailed(other, xixunyun.ErrTokenExpired, now)
+	require.NoError(t, err)
+	assert.Equal(t, RetryStatusFailed, other.Status)
+
+	// 超过截止时间仍未成功的任务被标记为过期
+	late, err := EnqueueRetry(RetryItem{Kind: JobTypeSign, Account: "carol", Deadline: now.Add(10 * time.Minute)}, now)
+	require.NoError(t, err)
+	expired, err := ExpireRetries(now.Add(5 * time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+	expired, err = ExpireRetries(now.Add(11 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, late.ID, expired[0].ID)
+
+	// 第一次重试就晚于截止时间时直接记录为过期
+	dave, err := EnqueueRetry(RetryItem{Kind: JobTypeSign, Account: "dave", Deadline: now.Add(30 * time.Second)}, now)
+	require.NoError(t, err)
+	assert.NotZero(t, dave.ID)
+	assert.Equal(t, RetryStatusExpired, dave.Status)
+	assert.Equal(t, 1, dave.Attempts)
+
+	// 最多尝试 1 次时不再重试
+	erin, err := EnqueueRetry(RetryItem{Kind: JobTypeSign, Account: "erin", MaxAttempts: 1, Deadline: now.Add(time.Hour)}, now)
+	require.NoError(t, err)
+	assert.Equal(t, RetryStatusExhausted, erin.Status)
+	assert.Equal(t, 1, erin.Attempts)
+
+	items, err := ListRetries("", 0)
+	require.NoError(t, err)
+	assert.Len(t, items, 5)
+	pending, err := ListRetries(RetryStatusPending, 0)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
@@ -2,32 +2,118 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 )
 
 type ScheduleTask struct {
-	ID        int
-	Account   string
-	Address   string
-	Latitude  string
-	Longitude string
-	Province  string
-	City      string
-	Remark    string
-	Comment   string
-	CronExpr  string
-	Enabled   int
-}
-
-// SignFunc 定义一个函数类型，用于执行签到操作
-type SignFunc func(ctx context.Context, account, address, latitude, longitude, province, city, remark, comment string) error
-
-// LoadSchedules 从数据库加载所有定时任务
-func LoadSchedules() ([]ScheduleTask, error) {
-	rows, err := db.Query(`SELECT id, account, address, latitude, longitude, province, city, remark, comment, cron_expr, enabled FROM schedules WHERE enabled=1`)
+	ID       int
+	Account  string
+	CronExpr string
+	Enabled  int
+
+	// JobType 为任务类型（JobTypeSign 等），Payload 为该类型的 JSON 参数。
+	JobType string
+	Payload string
+
+	// Timezone 为该任务使用的时区（如 Asia/Shanghai），为空时使用调度器的默认时区。
+	Timezone string
+	// DayPolicy 决定哪些日期需要签到（everyday、workdays 或 calendar:<日历名>）。
+	DayPolicy string
+	// MissedPolicy 决定守护进程启动时如何处理停机期间错过的执行。
+	MissedPolicy string
+	// LastRunAt、LastResult 为最近一次执行的时间与结果（JobOutcome 及原因），从未执行时为零值。
+	LastRunAt  time.Time
+	LastResult string
+
+	// RunAt 不为零时为一次性任务，只在该时间执行一次，不使用 CronExpr；
+	// 执行（或错过后不再补执行）后写入 CompletedAt，之后不再加载。
+	RunAt       time.Time
+	CompletedAt time.Time
+
+	// CreatedAt 为任务的创建时间，从未执行过的任务以此判断是否错过了执行。
+	CreatedAt time.Time
+}
+
+// IsOneShot 报告是否为只执行一次的任务
+func (t ScheduleTask) IsOneShot() bool {
+	return !t.RunAt.IsZero()
+}
+
+// Completed 报告一次性任务是否已经执行完成
+func (t ScheduleTask) Completed() bool {
+	return !t.CompletedAt.IsZero()
+}
+
+// 错过执行时的处理策略
+const (
+	MissedPolicySkip    = "skip"        // 跳过，只记录日志
+	MissedPolicyRunOnce = "run-once"    // 补执行一次
+	MissedPolicyNotify  = "notify-only" // 只发送通知
+)
+
+// ValidMissedPolicy 判断是否为支持的错过执行处理策略
+func ValidMissedPolicy(policy string) bool {
+	switch policy {
+	case MissedPolicySkip, MissedPolicyRunOnce, MissedPolicyNotify:
+		return true
+	}
+	return false
+}
+
+// scheduleColumns 是读取 ScheduleTask 时使用的列，顺序与 scanSchedule 一致。
+const scheduleColumns = `id, IFNULL(account, ''), IFNULL(job_type, ''), IFNULL(payload, ''), IFNULL(cron_expr, ''), IFNULL(enabled, 0),
+        IFNULL(missed_policy, ''), IFNULL(last_run_at, ''), IFNULL(last_result, ''), IFNULL(day_policy, ''),
+        IFNULL(timezone, ''), IFNULL(run_at, ''), IFNULL(completed_at, ''), IFNULL(created_at, '')`
+
+func scanSchedule(scanner interface{ Scan(...interface{}) error }) (ScheduleTask, error) {
+	var t ScheduleTask
+	var lastRunAt, runAt, completedAt, createdAt string
+	err := scanner.Scan(&t.ID, &t.Account, &t.JobType, &t.Payload, &t.CronExpr, &t.Enabled,
+		&t.MissedPolicy, &lastRunAt, &t.LastResult, &t.DayPolicy, &t.Timezone, &runAt, &completedAt, &createdAt)
+	if t.JobType == "" {
+		t.JobType = JobTypeSign
+	}
+	if t.DayPolicy == "" {
+		t.DayPolicy = DayPolicyEveryday
+	}
+	if t.MissedPolicy == "" {
+		t.MissedPolicy = MissedPolicySkip
+	}
+	if lastRunAt != "" {
+		t.LastRunAt, _ = time.Parse(time.RFC3339, lastRunAt)
+	}
+	if runAt != "" {
+		t.RunAt, _ = time.Parse(time.RFC3339, runAt)
+	}
+	if completedAt != "" {
+		t.CompletedAt, _ = time.Parse(time.RFC3339, completedAt)
+	}
+	if createdAt != "" {
+		t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	}
+	return t, err
+}
+
+func querySchedules(where string, args ...interface{}) ([]ScheduleTask, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+	querySQL := `SELECT ` + scheduleColumns + ` FROM schedules`
+	if where != "" {
+		querySQL += " WHERE " + where
+	}
+	querySQL += " ORDER BY id"
+	rows, err := db.Query(querySQL, args...)
 	if err != nil {
 		return nil, fmt.Errorf("查询定时任务失败: %v", err)
 	}
@@ -35,82 +121,769 @@ func LoadSchedules() ([]ScheduleTask, error) {
 
 	var tasks []ScheduleTask
 	for rows.Next() {
-		var t ScheduleTask
-		err := rows.Scan(&t.ID, &t.Account, &t.Address, &t.Latitude, &t.Longitude, &t.Province, &t.City, &t.Remark, &t.Comment, &t.CronExpr, &t.Enabled)
+		t, err := scanSchedule(rows)
 		if err != nil {
 			return nil, fmt.Errorf("读取行数据失败: %v", err)
 		}
 		tasks = append(tasks, t)
 	}
-	return tasks, nil
+	return tasks, rows.Err()
 }
 
-// InitScheduler 初始化并启动定时任务调度
-func InitScheduler(signFunc SignFunc) (*cron.Cron, error) {
-	c := cron.New()
-	tasks, err := LoadSchedules()
+// LoadSchedules 从数据库加载所有已启用且未完成的定时任务
+func LoadSchedules() ([]ScheduleTask, error) {
+	return querySchedules("enabled = 1 AND IFNULL(completed_at, '') = ''")
+}
+
+// ListSchedules 返回所有定时任务（包括已停用和已完成的）
+func ListSchedules() ([]ScheduleTask, error) {
+	return querySchedules("")
+}
+
+// ErrScheduleNotFound 表示定时任务不存在
+var ErrScheduleNotFound = errors.New("定时任务不存在")
+
+// GetSchedule 按 ID 读取定时任务
+func GetSchedule(id int) (ScheduleTask, error) {
+	tasks, err := querySchedules("id = ?", id)
 	if err != nil {
-		return nil, err
+		return ScheduleTask{}, err
+	}
+	if len(tasks) == 0 {
+		return ScheduleTask{}, ErrScheduleNotFound
 	}
+	return tasks[0], nil
+}
 
-	for _, task := range tasks {
-		// 将任务添加到cron调度器中
-		t := task
-		_, err := c.AddFunc(t.CronExpr, func() {
-			// 执行签到操作
-			ctx := context.Background()
-			log.Printf("开始执行定时签到任务[%d]，账号：%s\n", t.ID, t.Account)
-			err := signFunc(ctx, t.Account, t.Address, t.Latitude, t.Longitude, t.Province, t.City, t.Remark, t.Comment)
-			if err != nil {
-				log.Printf("定时签到任务[%d]执行失败: %v\n", t.ID, err)
-			} else {
-				log.Printf("定时签到任务[%d]执行成功\n", t.ID)
-			}
-		})
+// ParseCronExpr 校验并解析 cron 表达式，支持标准 5 段格式及 @daily、@every 1h 等描述符。
+func ParseCronExpr(expr string) (cron.Schedule, error) {
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("cron 表达式 %q 无效: %v", expr, err)
+	}
+	return sched, nil
+}
+
+// AddSchedule 校验并保存一个定时任务，返回新任务的 ID
+func AddSchedule(t ScheduleTask) (int, error) {
+	if t.JobType == "" {
+		t.JobType = JobTypeSign
+	}
+	if !ValidJobType(t.JobType) {
+		return 0, fmt.Errorf("不支持的任务类型: %s", t.JobType)
+	}
+	if t.Account == "" && JobNeedsAccount(t.JobType) {
+		return 0, errors.New("账号不能为空")
+	}
+	if t.Payload == "" {
+		t.Payload = "{}"
+	}
+	if !json.Valid([]byte(t.Payload)) {
+		return 0, fmt.Errorf("任务参数不是有效的 JSON: %s", t.Payload)
+	}
+	if _, err := time.LoadLocation(t.Timezone); err != nil {
+		return 0, fmt.Errorf("时区 %q 无效: %v", t.Timezone, err)
+	}
+	if t.IsOneShot() {
+		if t.CronExpr != "" {
+			return 0, errors.New("一次性任务不能同时设置 cron 表达式")
+		}
+		if !t.RunAt.After(time.Now()) {
+			return 0, fmt.Errorf("执行时间 %s 已经过去", t.RunAt.Format(time.RFC3339))
+		}
+		if t.DayPolicy != "" && t.DayPolicy != DayPolicyEveryday {
+			return 0, errors.New("一次性任务不支持签到日期策略")
+		}
+	} else if _, err := t.schedule(); err != nil {
+		return 0, err
+	}
+	if t.MissedPolicy == "" {
+		t.MissedPolicy = MissedPolicySkip
+	}
+	if !ValidMissedPolicy(t.MissedPolicy) {
+		return 0, fmt.Errorf("不支持的错过执行处理策略: %s", t.MissedPolicy)
+	}
+	if t.DayPolicy == "" {
+		t.DayPolicy = DayPolicyEveryday
+	}
+	if !ValidDayPolicy(t.DayPolicy) {
+		return 0, fmt.Errorf("不支持的签到日期策略: %s", t.DayPolicy)
+	}
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return 0, err
+		}
+	}
+	var runAt interface{}
+	if t.IsOneShot() {
+		runAt = t.RunAt.UTC().Format(time.RFC3339)
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	res, err := db.Exec(`INSERT INTO schedules (account, job_type, payload, cron_expr, enabled, missed_policy, day_policy, timezone, run_at, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Account, t.JobType, t.Payload, t.CronExpr, t.Enabled, t.MissedPolicy, t.DayPolicy, t.Timezone, runAt,
+		t.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, fmt.Errorf("保存定时任务失败: %v", err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// execSchedule 对单个定时任务执行更新语句，任务不存在时返回 ErrScheduleNotFound
+func execSchedule(querySQL string, args ...interface{}) error {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return err
+		}
+	}
+	res, err := db.Exec(querySQL, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// DeleteSchedule 删除定时任务
+func DeleteSchedule(id int) error {
+	return execSchedule(`DELETE FROM schedules WHERE id = ?`, id)
+}
+
+// SetScheduleEnabled 启用或停用定时任务
+func SetScheduleEnabled(id int, enabled bool) error {
+	v := 0
+	if enabled {
+		v = 1
+	}
+	return execSchedule(`UPDATE schedules SET enabled = ? WHERE id = ?`, v, id)
+}
+
+// SetScheduleMissedPolicy 修改定时任务错过执行时的处理策略
+func SetScheduleMissedPolicy(id int, policy string) error {
+	if !ValidMissedPolicy(policy) {
+		return fmt.Errorf("不支持的错过执行处理策略: %s", policy)
+	}
+	return execSchedule(`UPDATE schedules SET missed_policy = ? WHERE id = ?`, policy, id)
+}
+
+// SetScheduleDayPolicy 修改定时任务的签到日期策略
+func SetScheduleDayPolicy(id int, policy string) error {
+	if !ValidDayPolicy(policy) {
+		return fmt.Errorf("不支持的签到日期策略: %s", policy)
+	}
+	return execSchedule(`UPDATE schedules SET day_policy = ? WHERE id = ?`, policy, id)
+}
+
+// SetScheduleTimezone 修改定时任务的时区，为空时使用调度器的默认时区
+func SetScheduleTimezone(id int, tz string) error {
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("时区 %q 无效: %v", tz, err)
+	}
+	return execSchedule(`UPDATE schedules SET timezone = ? WHERE id = ?`, tz, id)
+}
+
+// cronSpec 返回交给 cron 解析的表达式，任务设置了时区时加上 CRON_TZ 前缀。
+func (t ScheduleTask) cronSpec() string {
+	if t.Timezone == "" || strings.HasPrefix(t.CronExpr, "CRON_TZ=") || strings.HasPrefix(t.CronExpr, "TZ=") {
+		return t.CronExpr
+	}
+	return "CRON_TZ=" + t.Timezone + " " + t.CronExpr
+}
+
+// schedule 返回任务的执行计划：一次性任务只在 RunAt 执行，其余按 cron 表达式执行。
+func (t ScheduleTask) schedule() (cron.Schedule, error) {
+	if t.IsOneShot() {
+		return onceSchedule{at: t.RunAt}, nil
+	}
+	return ParseCronExpr(t.cronSpec())
+}
+
+// onceSchedule 是只在 at 执行一次的 cron.Schedule，之后 Next 返回零值，cron 不会再触发。
+type onceSchedule struct {
+	at time.Time
+}
+
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// Location 返回任务使用的时区，未设置或无效时返回 def。
+func (t ScheduleTask) Location(def *time.Location) *time.Location {
+	if t.Timezone == "" {
+		return def
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return def
+	}
+	return loc
+}
+
+// RecordScheduleRun 记录定时任务最近一次执行的时间与结果
+func RecordScheduleRun(id int, at time.Time, result string) error {
+	return execSchedule(`UPDATE schedules SET last_run_at = ?, last_result = ? WHERE id = ?`,
+		at.UTC().Format(time.RFC3339), result, id)
+}
+
+// CompleteSchedule 将一次性任务标记为已完成，同时记录执行时间与结果
+func CompleteSchedule(id int, at time.Time, result string) error {
+	return execSchedule(`UPDATE schedules SET completed_at = ?, last_run_at = ?, last_result = ? WHERE id = ?`,
+		at.UTC().Format(time.RFC3339), at.UTC().Format(time.RFC3339), result, id)
+}
+
+// NextRunTimes 返回定时任务在 from 之后实际会签到的 n 次时间，按签到日期策略跳过不需要签到的日期。
+// 未设置时区的任务按 from 所在的时区计算，返回的时间位于任务的时区。已完成的一次性任务没有下次执行时间。
+func NextRunTimes(t ScheduleTask, from time.Time, n int) ([]time.Time, error) {
+	if t.Completed() {
+		return nil, nil
+	}
+	loc := t.Location(from.Location())
+	sched, err := t.schedule()
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, n)
+	next := from
+	// 限制查找次数，避免日历中整月放假时长时间循环
+	for i := 0; len(times) < n && i < n*100; i++ {
+		next = sched.Next(next)
+		if next.IsZero() {
+			break
+		}
+		ok, _, err := ShouldSignOn(t.DayPolicy, next.In(loc))
 		if err != nil {
-			log.Printf("添加定时任务[%d]失败: %v\n", t.ID, err)
+			return nil, err
+		}
+		if ok {
+			times = append(times, next.In(loc))
 		}
 	}
+	return times, nil
+}
 
-	c.Start()
-	return c, nil
+// MissedFireTime 返回 cron 表达式在 last 之后、now 之前（含）最近一次应执行的时间，
+// 只考虑 now 之前 grace 时间内的执行。没有错过的执行时返回 false。
+func MissedFireTime(expr string, last, now time.Time, grace time.Duration) (time.Time, bool) {
+	if last.IsZero() || grace <= 0 {
+		return time.Time{}, false
+	}
+	sched, err := ParseCronExpr(expr)
+	if err != nil {
+		return time.Time{}, false
+	}
+	since := last
+	if floor := now.Add(-grace); since.Before(floor) {
+		since = floor
+	}
+	var missed time.Time
+	for next := sched.Next(since); !next.IsZero() && !next.After(now); next = sched.Next(next) {
+		missed = next
+	}
+	return missed, !missed.IsZero()
 }
 
-// PerformSign 执行实际的签到操作
-func PerformSign(ctx context.Context, account string, address string, latitude string, longitude string, province string, city string, remark string, comment string) error {
-	// 检查必要参数是否存在
-	if account == "" || address == "" {
-		return fmt.Errorf("账号和地址不能为空")
+// metaSchedulesRevision 是 app_meta 中记录 schedules 表修改次数的键，由数据库触发器维护。
+const metaSchedulesRevision = "schedules_rev"
+
+// SchedulesRevision 返回 schedules 表的修改版本号，增删任务或修改任务配置后该值都会增加，记录执行结果不会。
+func SchedulesRevision() (string, error) {
+	return getMeta(metaSchedulesRevision)
+}
+
+// fingerprint 返回影响调度和执行的字段摘要，用于重新加载时判断任务是否被修改。
+func (t ScheduleTask) fingerprint() string {
+	return fmt.Sprintf("%q", []string{t.Account, t.JobType, t.Payload, t.CronExpr, t.DayPolicy, t.Timezone, t.RunAt.String()})
+}
+
+// ReloadResult 描述一次重新加载对调度器的改动。
+type ReloadResult struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Updated int `json:"updated"`
+	Total   int `json:"total"`
+}
+
+// Changed 报告本次重新加载是否改动了调度器。
+func (r ReloadResult) Changed() bool {
+	return r.Added+r.Removed+r.Updated > 0
+}
+
+// Job 返回定时任务按计划执行时交给 JobHandler 的任务
+func (t ScheduleTask) Job(trigger string, planned time.Time) Job {
+	return Job{ScheduleID: t.ID, Type: t.JobType, Account: t.Account, Payload: t.Payload, Trigger: trigger, PlannedAt: planned}
+}
+
+// Scheduler 是基于 cron 的定时任务调度器，按任务类型交给对应的 JobHandler 执行。
+type Scheduler struct {
+	cron     *cron.Cron
+	handlers JobHandlers
+
+	// ctx 传递给每个任务，Cancel 时取消仍在执行的任务。
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu 保护 entries 与 revision，同时保证重新加载串行执行。
+	mu       sync.Mutex
+	entries  map[int]scheduledEntry
+	revision string
+
+	// loc 是未设置时区的任务使用的默认时区
+	loc *time.Location
+
+	// catchUp 跟踪启动时补执行的任务，Stop 时与 cron 中的任务一起等待。
+	catchUp      sync.WaitGroup
+	catchUpGrace time.Duration
+	notifyMissed MissedFunc
+
+	// holder 不为空时启用主节点租约：只有持有 SchedulerLease 的实例执行任务，
+	// leaseExpires 为本实例租约的过期时间，由 heartbeat 定期续约。
+	holder        string
+	leaseTTL      time.Duration
+	leaseMu       sync.Mutex
+	leaseExpires  time.Time
+	stopHeartbeat chan struct{}
+	heartbeatDone chan struct{}
+}
+
+// MissedFunc 在定时任务错过执行且策略为 notify-only 时被调用。
+type MissedFunc func(t ScheduleTask, missedAt time.Time)
+
+// SchedulerOption 用于配置 Scheduler
+type SchedulerOption func(*Scheduler)
+
+// WithLocation 设置未单独设置时区的任务使用的时区，默认使用本地时区。
+func WithLocation(loc *time.Location) SchedulerOption {
+	return func(s *Scheduler) {
+		s.loc = loc
+	}
+}
+
+// WithCatchUp 启用错过执行的补偿：启动时检查每个任务在 grace 时间内是否错过了执行，
+// 并按任务的 MissedPolicy 处理。notify 可以为 nil。
+func WithCatchUp(grace time.Duration, notify MissedFunc) SchedulerOption {
+	return func(s *Scheduler) {
+		s.catchUpGrace = grace
+		s.notifyMissed = notify
+	}
+}
+
+// WithLease 启用主节点租约，用于多个实例共用同一个数据库：只有持有租约的实例执行任务，
+// 每隔 ttl/3 续约一次；主节点停止续约超过 ttl 后由其他实例接管，并补执行期间错过的任务。
+// holder 为本实例的唯一标识。
+func WithLease(holder string, ttl time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.holder = holder
+		s.leaseTTL = ttl
 	}
+}
+
+// scheduledEntry 记录已加入 cron 的任务及其对应的条目。
+type scheduledEntry struct {
+	id   cron.EntryID
+	task ScheduleTask
+}
 
-	// 模拟签到请求或执行签到逻辑
-	// 例如，这里可以通过 HTTP 请求发送签到数据到服务器
-	log.Printf("执行签到：账号=%s, 地址=%s, 纬度=%s, 经度=%s, 省=%s, 市=%s, 备注=%s, 评论=%s\n",
-		account, address, latitude, longitude, province, city, remark, comment)
+// InitScheduler 初始化并启动定时任务调度
+func InitScheduler(handlers JobHandlers, opts ...SchedulerOption) (*Scheduler, error) {
+	logger := cron.PrintfLogger(log.Default())
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		handlers: handlers,
+		ctx:      ctx,
+		cancel:   cancel,
+		entries:  map[int]scheduledEntry{},
+		loc:      time.Local,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.cron = cron.New(cron.WithLocation(s.loc), cron.WithLogger(logger), cron.WithChain(cron.Recover(logger)))
 
-	// 模拟签到成功逻辑
-	// 如果需要，可以将签到结果写入数据库
-	err := LogSignResult(account, address, "签到成功")
+	if _, _, err := s.reload(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if s.holder == "" {
+		s.catchUpMissed(time.Now().In(s.loc))
+	} else {
+		s.renewLease()
+		if lease, err := GetLease(SchedulerLease); err == nil && lease.Holder != s.holder {
+			log.Printf("主节点为 %s，本实例 %s 处于待命状态\n", lease.Holder, s.holder)
+		}
+		s.stopHeartbeat = make(chan struct{})
+		s.heartbeatDone = make(chan struct{})
+		go s.heartbeat()
+	}
+	s.cron.Start()
+	return s, nil
+}
+
+// IsLeader 报告本实例是否应当执行任务。未启用租约时总是返回 true。
+func (s *Scheduler) IsLeader() bool {
+	if s.holder == "" {
+		return true
+	}
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+	return time.Now().Before(s.leaseExpires)
+}
+
+// heartbeat 定期续约或尝试接管租约，直到 Stop
+func (s *Scheduler) heartbeat() {
+	defer close(s.heartbeatDone)
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopHeartbeat:
+			return
+		case <-ticker.C:
+			s.renewLease()
+		}
+	}
+}
+
+// renewLease 续约或尝试获取租约。续约失败（如数据库暂时不可用）时保持现状，直到租约自然过期。
+// 成为主节点时补执行停机或切换期间错过的任务。
+func (s *Scheduler) renewLease() {
+	now := time.Now()
+	ok, err := AcquireLease(SchedulerLease, s.holder, s.leaseTTL, now)
+	s.leaseMu.Lock()
+	wasLeader := now.Before(s.leaseExpires)
+	switch {
+	case err != nil:
+		log.Printf("续约主节点租约失败: %v\n", err)
+	case ok:
+		s.leaseExpires = now.Add(s.leaseTTL)
+	default:
+		s.leaseExpires = time.Time{}
+	}
+	isLeader := now.Before(s.leaseExpires)
+	s.leaseMu.Unlock()
+
+	switch {
+	case isLeader && !wasLeader:
+		log.Printf("实例 %s 成为主节点，开始执行定时任务\n", s.holder)
+		// 先重新加载，避免按过期的任务状态重复补执行原主节点已完成的任务
+		if _, _, err := s.reload(); err != nil {
+			log.Printf("重新加载定时任务失败: %v\n", err)
+		}
+		s.catchUpMissed(now.In(s.loc))
+	case !isLeader && wasLeader:
+		log.Printf("实例 %s 不再是主节点，暂停执行定时任务\n", s.holder)
+	}
+}
+
+// Reload 从数据库重新加载已启用的定时任务，并与当前的 cron 条目对账：
+// 新增的任务加入调度，已删除或停用的任务移出调度，内容有变化的任务替换为新的条目。
+// 调度未变的任务保留原条目，但更新其上次执行时间、错过执行时的策略等状态。
+// 移出调度不会中断正在执行的任务。新加入调度且执行时间已过的一次性任务(如添加后才启用)
+// 不会再被 cron 触发，立即按错过执行的策略处理。
+func (s *Scheduler) Reload() (ReloadResult, error) {
+	result, added, err := s.reload()
 	if err != nil {
-		return fmt.Errorf("记录签到结果失败: %v", err)
+		return result, err
 	}
+	if s.IsLeader() {
+		now := time.Now().In(s.loc)
+		for _, t := range added {
+			if t.IsOneShot() && !t.RunAt.After(now) {
+				s.catchUpTask(t, now)
+			}
+		}
+	}
+	return result, nil
+}
 
-	return nil
+// reload 实现 Reload 的对账，返回新加入调度的任务。启动与成为主节点时随后会调用 catchUpMissed，
+// 因此不处理错过执行的任务。
+func (s *Scheduler) reload() (ReloadResult, []ScheduleTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 先读取版本号再读取任务，避免漏掉两次读取之间发生的修改。
+	revision, err := SchedulesRevision()
+	if err != nil {
+		return ReloadResult{}, nil, err
+	}
+	tasks, err := LoadSchedules()
+	if err != nil {
+		return ReloadResult{}, nil, err
+	}
+
+	var result ReloadResult
+	var added []ScheduleTask
+	wanted := make(map[int]ScheduleTask, len(tasks))
+	for _, t := range tasks {
+		wanted[t.ID] = t
+	}
+	replaced := map[int]bool{}
+	for id, e := range s.entries {
+		t, ok := wanted[id]
+		if ok && t.fingerprint() == e.task.fingerprint() {
+			e.task = t
+			s.entries[id] = e
+			continue
+		}
+		s.cron.Remove(e.id)
+		delete(s.entries, id)
+		if ok {
+			replaced[id] = true
+		} else {
+			result.Removed++
+		}
+	}
+	for _, t := range tasks {
+		if _, ok := s.entries[t.ID]; ok {
+			continue
+		}
+		if err := s.add(t); err != nil {
+			log.Printf("添加定时任务[%d]失败: %v\n", t.ID, err)
+			continue
+		}
+		added = append(added, t)
+		if replaced[t.ID] {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+	}
+	result.Total = len(s.entries)
+	s.revision = revision
+	return result, added, nil
 }
 
-// LogSignResult 记录签到结果到数据库
-func LogSignResult(account, address, result string) error {
-	if db == nil {
-		return fmt.Errorf("数据库未初始化")
+// ReloadIfChanged 仅在 schedules 表的版本号变化时重新加载，返回是否执行了重新加载。
+func (s *Scheduler) ReloadIfChanged() (ReloadResult, bool, error) {
+	revision, err := SchedulesRevision()
+	if err != nil {
+		return ReloadResult{}, false, err
 	}
+	s.mu.Lock()
+	unchanged := revision == s.revision
+	s.mu.Unlock()
+	if unchanged {
+		return ReloadResult{}, false, nil
+	}
+	result, err := s.Reload()
+	return result, err == nil, err
+}
 
-	insertSQL := `
-    INSERT INTO sign_logs (account, address, result, timestamp)
-    VALUES (?, ?, ?, datetime('now'))
-    `
-	_, err := db.Exec(insertSQL, account, address, result)
+// add 将任务添加到cron调度器中，调用方需持有 s.mu
+func (s *Scheduler) add(t ScheduleTask) error {
+	sched, err := t.schedule()
 	if err != nil {
-		return fmt.Errorf("写入签到日志失败: %v", err)
+		return err
 	}
+	job := &scheduledJob{s: s, task: t, sched: sched, next: sched.Next(time.Now().In(s.loc))}
+	id := s.cron.Schedule(sched, job)
+	s.entries[t.ID] = scheduledEntry{id: id, task: t}
 	return nil
 }
+
+// scheduledJob 是加入 cron 的任务，自行跟踪计划执行时间以便记录调度延迟。
+type scheduledJob struct {
+	s     *Scheduler
+	task  ScheduleTask
+	sched cron.Schedule
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Run 实现 cron.Job。cron 在到达计划时间后触发任务，并以触发时刻计算下一次时间，这里保持相同的计算方式。
+func (j *scheduledJob) Run() {
+	now := time.Now().In(j.s.loc)
+	j.mu.Lock()
+	planned := j.next
+	j.next = j.sched.Next(now)
+	j.mu.Unlock()
+	j.s.run(j.task, planned, JobTriggerSchedule)
+}
+
+// run 执行一次定时任务，并记录到 job_runs 与任务的最近执行结果中
+func (s *Scheduler) run(t ScheduleTask, planned time.Time, trigger string) {
+	// 只有主节点执行任务；同一次执行在切换主节点前后也只会被认领一次。
+	if !s.IsLeader() {
+		return
+	}
+	if s.holder != "" && !planned.IsZero() {
+		claimed, err := ClaimJobRun(t.ID, planned, s.holder, time.Now())
+		if err != nil {
+			log.Printf("定时任务[%d]认领失败，继续执行: %v\n", t.ID, err)
+		} else if !claimed {
+			log.Printf("定时任务[%d]在 %s 的执行已由其他实例认领，跳过\n", t.ID, planned.Format("2006-01-02 15:04:05"))
+			return
+		}
+	}
+
+	start := time.Now().In(t.Location(s.loc))
+	record := JobRun{ScheduleID: t.ID, Account: t.Account, Trigger: trigger, PlannedAt: planned, StartedAt: start}
+	defer func() {
+		result := record.Outcome
+		if record.Error != "" {
+			result += ": " + record.Error
+		}
+		finish := RecordScheduleRun
+		if t.IsOneShot() {
+			finish = CompleteSchedule
+		}
+		if err := finish(t.ID, start, result); err != nil {
+			log.Printf("记录定时任务[%d]执行结果失败: %v\n", t.ID, err)
+		}
+		if _, err := RecordJobRun(record); err != nil {
+			log.Printf("记录定时任务[%d]执行历史失败: %v\n", t.ID, err)
+		}
+	}()
+
+	// 按计划执行的日期判断，补执行前一天错过的任务时不应按今天的日历
+	day := start
+	if !planned.IsZero() {
+		day = planned.In(t.Location(s.loc))
+	}
+	if sign, reason, err := ShouldSignOn(t.DayPolicy, day); err != nil {
+		// 判断失败时仍然执行，宁可多签一次也不漏签
+		log.Printf("定时任务[%d]查询节假日日历失败，继续执行: %v\n", t.ID, err)
+	} else if !sign {
+		log.Printf("定时任务[%d]今日无需执行(%s)，已跳过\n", t.ID, reason)
+		record.Outcome, record.Error = JobOutcomeSkipped, reason
+		return
+	}
+
+	log.Printf("开始执行定时任务[%d](%s)，账号：%s\n", t.ID, t.JobType, t.Account)
+	err := s.handlers.Run(s.ctx, t.Job(trigger, planned))
+	record.Duration = time.Since(start)
+	if err != nil {
+		log.Printf("定时任务[%d]执行失败: %v\n", t.ID, err)
+		record.Outcome, record.ErrorClass, record.Error = JobOutcomeFailed, ErrorClass(err), err.Error()
+	} else {
+		log.Printf("定时任务[%d]执行成功\n", t.ID)
+		record.Outcome = JobOutcomeSuccess
+	}
+}
+
+// catchUpMissed 检查已加载的任务在停机期间是否错过了执行，并按各自的策略处理。
+// 不补执行的错过记录为已跳过并更新上次执行时间，重启或切换主节点后不会再次处理。
+// 错过执行的一次性任务之后不会再执行：补执行后完成，不补执行或超出宽限时间时标记为已跳过并完成。
+func (s *Scheduler) catchUpMissed(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		s.catchUpTask(e.task, now)
+	}
+}
+
+// catchUpTask 检查一个任务是否错过了执行，并按任务的策略处理
+func (s *Scheduler) catchUpTask(t ScheduleTask, now time.Time) {
+	var missedAt time.Time
+	if t.IsOneShot() {
+		if t.RunAt.After(now) {
+			return
+		}
+		missedAt = t.RunAt.In(t.Location(s.loc))
+		if s.catchUpGrace <= 0 || now.Sub(t.RunAt) > s.catchUpGrace {
+			log.Printf("一次性任务[%d]错过了 %s 的执行，已超出补执行的时间范围\n", t.ID, missedAt.Format("2006-01-02 15:04:05"))
+			s.skipMissed(t, missedAt, "错过执行时间")
+			return
+		}
+	} else {
+		// 从未执行过的任务从创建时起计算
+		last := t.LastRunAt
+		if last.IsZero() {
+			last = t.CreatedAt
+		}
+		var ok bool
+		if missedAt, ok = MissedFireTime(t.cronSpec(), last, now, s.catchUpGrace); !ok {
+			return
+		}
+		missedAt = missedAt.In(t.Location(s.loc))
+	}
+	if sign, reason, err := ShouldSignOn(t.DayPolicy, missedAt); err == nil && !sign {
+		log.Printf("定时任务[%d]错过了 %s 的执行，当天无需签到(%s)\n", t.ID, missedAt.Format("2006-01-02 15:04:05"), reason)
+		return
+	}
+	switch t.MissedPolicy {
+	case MissedPolicyRunOnce:
+		log.Printf("定时任务[%d]错过了 %s 的执行，现在补执行一次\n", t.ID, missedAt.Format("2006-01-02 15:04:05"))
+		s.catchUp.Add(1)
+		go func() {
+			defer s.catchUp.Done()
+			s.run(t, missedAt, JobTriggerCatchUp)
+		}()
+	case MissedPolicyNotify:
+		log.Printf("定时任务[%d]错过了 %s 的执行，仅发送通知\n", t.ID, missedAt.Format("2006-01-02 15:04:05"))
+		if s.notifyMissed != nil {
+			s.notifyMissed(t, missedAt)
+		}
+		s.skipMissed(t, missedAt, "错过执行时间，已发送通知")
+	default:
+		log.Printf("定时任务[%d]错过了 %s 的执行，已跳过\n", t.ID, missedAt.Format("2006-01-02 15:04:05"))
+		s.skipMissed(t, missedAt, "错过执行时间")
+	}
+}
+
+// skipMissed 将错过且不再补执行的一次执行记录为已跳过，并更新任务的上次执行时间；一次性任务同时标记为完成。
+func (s *Scheduler) skipMissed(t ScheduleTask, missedAt time.Time, reason string) {
+	now := time.Now().In(t.Location(s.loc))
+	if _, err := RecordJobRun(JobRun{ScheduleID: t.ID, Account: t.Account, Trigger: JobTriggerCatchUp, PlannedAt: missedAt,
+		StartedAt: now, Outcome: JobOutcomeSkipped, Error: reason}); err != nil {
+		log.Printf("记录定时任务[%d]执行历史失败: %v\n", t.ID, err)
+	}
+	finish := RecordScheduleRun
+	if t.IsOneShot() {
+		finish = CompleteSchedule
+	}
+	if err := finish(t.ID, now, JobOutcomeSkipped+": "+reason); err != nil {
+		log.Printf("记录定时任务[%d]执行结果失败: %v\n", t.ID, err)
+	}
+}
+
+// Len 返回已加入调度的任务数量
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Stop 停止调度新的任务，返回的 context 在所有正在执行的任务结束后关闭。
+// 启用租约时停止续约，并在任务结束后释放租约，便于其他实例立即接管。
+func (s *Scheduler) Stop() context.Context {
+	cronDone := s.cron.Stop()
+	if s.stopHeartbeat != nil {
+		close(s.stopHeartbeat)
+		<-s.heartbeatDone
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-cronDone.Done()
+		s.catchUp.Wait()
+		if s.holder != "" {
+			s.leaseMu.Lock()
+			s.leaseExpires = time.Time{}
+			s.leaseMu.Unlock()
+			if err := ReleaseLease(SchedulerLease, s.holder); err != nil {
+				log.Println(err)
+			}
+		}
+		cancel()
+	}()
+	return ctx
+}
+
+// Cancel 取消仍在执行的任务，用于等待超时后强制退出。
+func (s *Scheduler) Cancel() {
+	s.cancel()
+}
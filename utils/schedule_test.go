@@ -0,0 +1,447 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerRunsAndDrains(t *testing.T) {
+	openTestDB(t)
+	_, err := db.Exec(`INSERT INTO schedules (account, job_type, payload, cron_expr, enabled) VALUES
+        ('user', 'sign', '{"address":"江苏省南京市"}', '@every 1s', 1),
+        ('other', 'sign', '{}', '@every 1s', 0)`)
+	require.NoError(t, err)
+
+	started := make(chan string, 10)
+	release := make(chan struct{})
+	s, err := InitScheduler(JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error {
+		var p SignPayload
+		require.NoError(t, job.Decode(&p))
+		assert.Equal(t, "江苏省南京市", p.Address)
+		started <- job.Account
+		<-release
+		return nil
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Len())
+
+	select {
+	case account := <-started:
+		assert.Equal(t, "user", account)
+	case <-time.After(3 * time.Second):
+		t.Fatal("定时任务未被触发")
+	}
+
+	// 任务仍在执行时 Stop 返回的 context 不应结束
+	drained := s.Stop()
+	select {
+	case <-drained.Done():
+		t.Fatal("任务尚未完成，调度器不应停止")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-drained.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("任务完成后调度器未停止")
+	}
+}
+
+func TestScheduleCRUD(t *testing.T) {
+	openTestDB(t)
+
+	_, err := AddSchedule(ScheduleTask{Account: "user", CronExpr: "61 8 * * *", Enabled: 1})
+	assert.Error(t, err, "非法的 cron 表达式应被拒绝")
+
+	_, err = AddSchedule(ScheduleTask{Account: "user", JobType: "unknown", CronExpr: "30 8 * * *", Enabled: 1})
+	assert.Error(t, err, "未知的任务类型应被拒绝")
+	_, err = AddSchedule(ScheduleTask{Account: "user", Payload: "{", CronExpr: "30 8 * * *", Enabled: 1})
+	assert.Error(t, err, "无效的任务参数应被拒绝")
+	_, err = AddSchedule(ScheduleTask{JobType: JobTypeQuery, CronExpr: "30 8 * * *", Enabled: 1})
+	assert.Error(t, err, "需要账号的任务缺少账号应被拒绝")
+
+	id, err := AddSchedule(ScheduleTask{Account: "user", Payload: `{"address":"江苏省南京市"}`, CronExpr: "30 8 * * *", Enabled: 1})
+	require.NoError(t, err)
+	other, err := AddSchedule(ScheduleTask{JobType: JobTypeSchoolRefresh, CronExpr: "@daily", Enabled: 0})
+	require.NoError(t, err)
+
+	tasks, err := ListSchedules()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	enabled, err := LoadSchedules()
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, id, enabled[0].ID)
+	assert.Equal(t, JobTypeSign, enabled[0].JobType)
+	assert.JSONEq(t, `{"address":"江苏省南京市"}`, enabled[0].Payload)
+
+	require.NoError(t, SetScheduleEnabled(other, true))
+	task, err := GetSchedule(other)
+	require.NoError(t, err)
+	assert.Equal(t, 1, task.Enabled)
+
+	require.NoError(t, DeleteSchedule(id))
+	_, err = GetSchedule(id)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	assert.ErrorIs(t, DeleteSchedule(id), ErrScheduleNotFound)
+	assert.ErrorIs(t, SetScheduleEnabled(id, false), ErrScheduleNotFound)
+}
+
+func TestNextRunTimes(t *testing.T) {
+	openTestDB(t)
+	from := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) // 周五
+	times, err := NextRunTimes(ScheduleTask{CronExpr: "30 8 * * 1-5", DayPolicy: DayPolicyEveryday}, from, 2)
+	require.NoError(t, err)
+	require.Len(t, times, 2)
+	assert.Equal(t, time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC), times[0])
+	assert.Equal(t, time.Date(2026, 10, 20, 8, 30, 0, 0, time.UTC), times[1])
+}
+
+func TestSchedulerReload(t *testing.T) {
+	openTestDB(t)
+	first, err := AddSchedule(ScheduleTask{Account: "user", CronExpr: "0 8 * * *", Enabled: 1})
+	require.NoError(t, err)
+
+	s, err := InitScheduler(JobHandlers{})
+	require.NoError(t, err)
+	defer s.Stop()
+	assert.Equal(t, 1, s.Len())
+
+	// 未修改时不应重新加载
+	_, reloaded, err := s.ReloadIfChanged()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// 直接修改数据库也应被触发器记录
+	_, err = db.Exec(`UPDATE schedules SET cron_expr = '0 9 * * *' WHERE id = ?`, first)
+	require.NoError(t, err)
+	second, err := AddSchedule(ScheduleTask{Account: "other", CronExpr: "@daily", Enabled: 1})
+	require.NoError(t, err)
+
+	result, reloaded, err := s.ReloadIfChanged()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, ReloadResult{Added: 1, Updated: 1, Total: 2}, result)
+
+	require.NoError(t, SetScheduleEnabled(second, false))
+	result, err = s.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, ReloadResult{Removed: 1, Total: 1}, result)
+
+	result, err = s.Reload()
+	require.NoError(t, err)
+	assert.False(t, result.Changed())
+}
+
+func TestSchedulesRevision(t *testing.T) {
+	openTestDB(t)
+	id, err := AddSchedule(ScheduleTask{Account: "user", CronExpr: "0 8 * * *", Enabled: 1})
+	require.NoError(t, err)
+	rev, err := SchedulesRevision()
+	require.NoError(t, err)
+
+	// 记录执行结果不算配置变更
+	require.NoError(t, RecordScheduleRun(id, time.Now(), JobOutcomeSuccess))
+	got, err := SchedulesRevision()
+	require.NoError(t, err)
+	assert.Equal(t, rev, got)
+
+	require.NoError(t, SetScheduleEnabled(id, false))
+	got, err = SchedulesRevision()
+	require.NoError(t, err)
+	assert.NotEqual(t, rev, got)
+}
+
+func TestMissedFireTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	last := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+
+	missed, ok := MissedFireTime("0 8 * * *", last, now, 3*time.Hour)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC), missed)
+
+	// 错过的执行超出宽限时间
+	_, ok = MissedFireTime("0 8 * * *", last, now, time.Hour)
+	assert.False(t, ok)
+
+	// 今天已经执行过
+	_, ok = MissedFireTime("0 8 * * *", now.Add(-time.Hour), now, 3*time.Hour)
+	assert.False(t, ok)
+
+	// 从未执行过的任务不补执行
+	_, ok = MissedFireTime("0 8 * * *", time.Time{}, now, 3*time.Hour)
+	assert.False(t, ok)
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	openTestDB(t)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	policies := []string{MissedPolicyRunOnce, MissedPolicyNotify, MissedPolicySkip}
+	ids := map[string]int{}
+	for _, policy := range policies {
+		// 每分钟执行的任务在一小时的宽限时间内必然错过了执行
+		id, err := AddSchedule(ScheduleTask{Account: policy, CronExpr: "* * * * *", Enabled: 1, MissedPolicy: policy})
+		require.NoError(t, err)
+		require.NoError(t, RecordScheduleRun(id, yesterday, SignStatusSuccess))
+		ids[policy] = id
+	}
+
+	signed := make(chan string, 10)
+	var notified []string
+	s, err := InitScheduler(JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error {
+		assert.Equal(t, JobTriggerCatchUp, job.Trigger)
+		signed <- job.Account
+		return nil
+	}}, WithCatchUp(time.Hour, func(t ScheduleTask, missedAt time.Time) {
+		notified = append(notified, t.Account)
+	}))
+	require.NoError(t, err)
+	<-s.Stop().Done()
+
+	close(signed)
+	var accounts []string
+	for account := range signed {
+		accounts = append(accounts, account)
+	}
+	assert.Equal(t, []string{MissedPolicyRunOnce}, accounts)
+	assert.Equal(t, []string{MissedPolicyNotify}, notified)
+
+	task, err := GetSchedule(ids[MissedPolicyRunOnce])
+	require.NoError(t, err)
+	assert.Equal(t, JobOutcomeSuccess, task.LastResult)
+	assert.WithinDuration(t, time.Now(), task.LastRunAt, time.Minute)
+
+	runs, err := QueryJobRuns(JobRunFilter{ScheduleID: ids[MissedPolicyRunOnce]})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, JobTriggerCatchUp, runs[0].Trigger)
+	assert.True(t, runs[0].PlannedAt.Before(runs[0].StartedAt))
+
+	// 不补执行的错过同样记录下来，再次启动时不会重复通知
+	for _, policy := range []string{MissedPolicyNotify, MissedPolicySkip} {
+		task, err := GetSchedule(ids[policy])
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), task.LastRunAt, time.Minute, policy)
+		runs, err := QueryJobRuns(JobRunFilter{ScheduleID: ids[policy]})
+		require.NoError(t, err)
+		require.Len(t, runs, 1, policy)
+		assert.Equal(t, JobOutcomeSkipped, runs[0].Outcome, policy)
+	}
+	notified = nil
+	s, err = InitScheduler(JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error { return nil }},
+		WithCatchUp(time.Hour, func(t ScheduleTask, missedAt time.Time) {
+			notified = append(notified, t.Account)
+		}))
+	require.NoError(t, err)
+	<-s.Stop().Done()
+	assert.Empty(t, notified)
+}
+
+func TestSchedulerCatchUpNeverRun(t *testing.T) {
+	openTestDB(t)
+	// 创建后从未执行过的任务以创建时间为起点判断是否错过执行
+	_, err := AddSchedule(ScheduleTask{Account: "old", CronExpr: "* * * * *", Enabled: 1, MissedPolicy: MissedPolicyRunOnce,
+		CreatedAt: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	_, err = AddSchedule(ScheduleTask{Account: "new", CronExpr: "0 0 1 1 *", Enabled: 1, MissedPolicy: MissedPolicyRunOnce})
+	require.NoError(t, err)
+
+	signed := make(chan string, 10)
+	s, err := InitScheduler(JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error {
+		signed <- job.Account
+		return nil
+	}}, WithCatchUp(3*time.Hour, nil))
+	require.NoError(t, err)
+	<-s.Stop().Done()
+
+	close(signed)
+	var accounts []string
+	for account := range signed {
+		accounts = append(accounts, account)
+	}
+	assert.Equal(t, []string{"old"}, accounts)
+}
+
+func TestScheduleTimezone(t *testing.T) {
+	openTestDB(t)
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	_, err = AddSchedule(ScheduleTask{Account: "user", CronExpr: "0 8 * * *", Timezone: "Mars/Olympus", Enabled: 1})
+	assert.Error(t, err, "无效的时区应被拒绝")
+
+	// 容器时区为 UTC 时，设置了时区的任务仍按北京时间 08:00 执行
+	from := time.Date(2026, 10, 16, 1, 0, 0, 0, time.UTC)
+	task := ScheduleTask{CronExpr: "0 8 * * *", Timezone: "Asia/Shanghai", DayPolicy: DayPolicyEveryday}
+	times, err := NextRunTimes(task, from, 1)
+	require.NoError(t, err)
+	require.Len(t, times, 1)
+	assert.Equal(t, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), times[0].UTC())
+	assert.Equal(t, shanghai, times[0].Location())
+
+	// 未设置时区的任务使用 from 所在的时区
+	task.Timezone = ""
+	times, err = NextRunTimes(task, from.In(shanghai), 1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), times[0].UTC())
+}
+
+func TestOneShotSchedule(t *testing.T) {
+	openTestDB(t)
+	now := time.Now()
+
+	_, err := AddSchedule(ScheduleTask{Account: "user", RunAt: now.Add(-time.Minute), Enabled: 1})
+	assert.Error(t, err, "已经过去的执行时间应被拒绝")
+	_, err = AddSchedule(ScheduleTask{Account: "user", RunAt: now.Add(time.Hour), CronExpr: "0 8 * * *", Enabled: 1})
+	assert.Error(t, err, "不能同时设置 cron 表达式")
+
+	soon, err := AddSchedule(ScheduleTask{Account: "soon", RunAt: now.Add(2 * time.Second), Enabled: 1})
+	require.NoError(t, err)
+	later, err := AddSchedule(ScheduleTask{Account: "later", RunAt: now.Add(48 * time.Hour), Enabled: 1})
+	require.NoError(t, err)
+
+	task, err := GetSchedule(later)
+	require.NoError(t, err)
+	times, err := NextRunTimes(task, now, 3)
+	require.NoError(t, err)
+	require.Len(t, times, 1)
+	assert.True(t, times[0].Equal(now.Add(48*time.Hour).Truncate(time.Second)))
+
+	ran := make(chan string, 10)
+	s, err := InitScheduler(JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error {
+		ran <- job.Account
+		return nil
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Len())
+	select {
+	case account := <-ran:
+		assert.Equal(t, "soon", account)
+	case <-time.After(5 * time.Second):
+		t.Fatal("一次性任务未被触发")
+	}
+	<-s.Stop().Done()
+
+	// 执行后标记为完成，不再加载
+	task, err = GetSchedule(soon)
+	require.NoError(t, err)
+	assert.True(t, task.Completed())
+	assert.Equal(t, JobOutcomeSuccess, task.LastResult)
+	tasks, err := LoadSchedules()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, later, tasks[0].ID)
+}
+
+func TestOneShotCatchUp(t *testing.T) {
+	openTestDB(t)
+	ids := map[string]int{}
+	for _, policy := range []string{MissedPolicyRunOnce, MissedPolicySkip} {
+		id, err := AddSchedule(ScheduleTask{Account: policy, RunAt: time.Now().Add(time.Hour), Enabled: 1, MissedPolicy: policy})
+		require.NoError(t, err)
+		ids[policy] = id
+	}
+	// 模拟守护进程停机期间错过了执行时间
+	_, err := db.Exec(`UPDATE schedules SET run_at = ?`, time.Now().Add(-10*time.Minute).UTC().Format(time.RFC3339))
+	require.NoError(t, err)
+
+	ran := make(chan string, 10)
+	s, err := InitScheduler(JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error {
+		ran <- job.Account
+		return nil
+	}}, WithCatchUp(time.Hour, nil))
+	require.NoError(t, err)
+	<-s.Stop().Done()
+	close(ran)
+	var accounts []string
+	for account := range ran {
+		accounts = append(accounts, account)
+	}
+	assert.Equal(t, []string{MissedPolicyRunOnce}, accounts)
+
+	for policy, outcome := range map[string]string{MissedPolicyRunOnce: JobOutcomeSuccess, MissedPolicySkip: JobOutcomeSkipped} {
+		task, err := GetSchedule(ids[policy])
+		require.NoError(t, err)
+		assert.True(t, task.Completed(), policy)
+		runs, err := QueryJobRuns(JobRunFilter{ScheduleID: ids[policy]})
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, outcome, runs[0].Outcome)
+	}
+}
+
+func TestSchedulerRunUsesPlannedDay(t *testing.T) {
+	openTestDB(t)
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	_, err := ImportCalendar("school", []CalendarDay{
+		{Date: yesterday.Format(calendarDateLayout), Type: DayTypeHoliday, Name: "放假"},
+		{Date: now.Format(calendarDateLayout), Type: DayTypeWorkday, Name: "补班"},
+	}, false)
+	require.NoError(t, err)
+	id, err := AddSchedule(ScheduleTask{Account: "user", CronExpr: "0 23 * * *", Enabled: 1, DayPolicy: DayPolicyCalendarPrefix + "school"})
+	require.NoError(t, err)
+	task, err := GetSchedule(id)
+	require.NoError(t, err)
+
+	ran := 0
+	s, err := InitScheduler(JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error {
+		ran++
+		return nil
+	}})
+	require.NoError(t, err)
+	defer func() { <-s.Stop().Done() }()
+
+	// 今天补执行昨天错过的任务时按昨天(放假)判断
+	s.run(task, yesterday, JobTriggerCatchUp)
+	assert.Zero(t, ran)
+	runs, err := QueryJobRuns(JobRunFilter{ScheduleID: id})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, JobOutcomeSkipped, runs[0].Outcome)
+
+	s.run(task, now, JobTriggerSchedule)
+	assert.Equal(t, 1, ran)
+}
+
+func TestReloadCatchesUpPastOneShot(t *testing.T) {
+	openTestDB(t)
+	ran := make(chan string, 10)
+	s, err := InitScheduler(JobHandlers{JobTypeSign: func(ctx context.Context, job Job) error {
+		ran <- job.Account
+		return nil
+	}}, WithCatchUp(time.Hour, nil))
+	require.NoError(t, err)
+
+	// 守护进程运行期间启用执行时间已过的一次性任务，重新加载时按错过执行的策略处理
+	ids := map[string]time.Duration{"recent": 10 * time.Minute, "old": 2 * time.Hour}
+	for account, ago := range ids {
+		id, err := AddSchedule(ScheduleTask{Account: account, RunAt: time.Now().Add(time.Hour), MissedPolicy: MissedPolicyRunOnce})
+		require.NoError(t, err)
+		_, err = db.Exec(`UPDATE schedules SET run_at = ? WHERE id = ?`, time.Now().Add(-ago).UTC().Format(time.RFC3339), id)
+		require.NoError(t, err)
+		require.NoError(t, SetScheduleEnabled(id, true))
+	}
+	result, err := s.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Added)
+	<-s.Stop().Done()
+	close(ran)
+
+	var accounts []string
+	for account := range ran {
+		accounts = append(accounts, account)
+	}
+	assert.Equal(t, []string{"recent"}, accounts)
+	tasks, err := ListSchedules()
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	for _, task := range tasks {
+		assert.True(t, task.Completed(), task.Account)
+	}
+}
@@ -0,0 +1,405 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 敏感字段（users.password、users.token）的静态加密。
+//
+// 加密密钥由主密码经 scrypt 派生，盐与参数保存在 app_meta 表中；密文格式为
+// "enc:v1:" + base64(nonce || AES-256-GCM 密文)。不带前缀的值视为明文，
+// 因此未加密的旧数据库可以照常使用，并可随时通过 `xixun db encrypt` 迁移。
+
+const (
+	secretPrefix = "enc:v1:"
+	// secretCheckPlain 用于校验主密码是否正确的已知明文。
+	secretCheckPlain = "xixunyunsign"
+
+	metaSecretKDF   = "secret_kdf"
+	metaSecretCheck = "secret_check"
+
+	// scrypt 参数：N=2^15, r=8, p=1，约占用 32MB 内存。
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// 主密码来源的环境变量。
+const (
+	EnvMasterKey     = "XIXUN_MASTER_KEY"
+	EnvMasterKeyFile = "XIXUN_MASTER_KEY_FILE"
+)
+
+var (
+	// ErrMasterKeyRequired 表示数据库已加密但未提供主密码。
+	ErrMasterKeyRequired = errors.New("数据库已加密，请通过环境变量 " + EnvMasterKey + " 或 " + EnvMasterKeyFile + " 提供主密码")
+	// ErrWrongMasterKey 表示主密码与数据库不匹配。
+	ErrWrongMasterKey = errors.New("主密码错误")
+	// ErrNotEncrypted 表示数据库尚未加密。
+	ErrNotEncrypted = errors.New("数据库未加密")
+	// ErrAlreadyEncrypted 表示数据库已经加密。
+	ErrAlreadyEncrypted = errors.New("数据库已加密")
+)
+
+// MasterPassphrase 从环境变量或密钥文件读取主密码，均未设置时返回空字符串。
+func MasterPassphrase() (string, error) {
+	if v := os.Getenv(EnvMasterKey); v != "" {
+		return v, nil
+	}
+	if path := os.Getenv(EnvMasterKeyFile); path != "" {
+		return ReadKeyFile(path)
+	}
+	return "", nil
+}
+
+// ReadKeyFile 读取密钥文件，去除首尾空白。
+func ReadKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("密钥文件 %s 为空", path)
+	}
+	return key, nil
+}
+
+// secretBox 封装派生出的 AEAD。
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+var (
+	boxMu    sync.Mutex
+	boxCache = map[string]*secretBox{}
+)
+
+// kdfParams 表示 app_meta 中保存的密钥派生参数。
+type kdfParams struct {
+	n, r, p int
+	salt    []byte
+}
+
+func (k kdfParams) String() string {
+	return fmt.Sprintf("scrypt$%d$%d$%d$%s", k.n, k.r, k.p, base64.StdEncoding.EncodeToString(k.salt))
+}
+
+func parseKDFParams(s string) (kdfParams, error) {
+	parts := strings.Split(s, "$")
+	if len(parts) != 5 || parts[0] != "scrypt" {
+		return kdfParams{}, fmt.Errorf("无法识别的密钥派生参数: %s", s)
+	}
+	var k kdfParams
+	var err error
+	if k.n, err = strconv.Atoi(parts[1]); err != nil {
+		return kdfParams{}, err
+	}
+	if k.r, err = strconv.Atoi(parts[2]); err != nil {
+		return kdfParams{}, err
+	}
+	if k.p, err = strconv.Atoi(parts[3]); err != nil {
+		return kdfParams{}, err
+	}
+	if k.salt, err = base64.StdEncoding.DecodeString(parts[4]); err != nil {
+		return kdfParams{}, err
+	}
+	return k, nil
+}
+
+func newKDFParams() (kdfParams, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return kdfParams{}, err
+	}
+	return kdfParams{n: scryptN, r: scryptR, p: scryptP, salt: salt}, nil
+}
+
+// deriveBox 由主密码与派生参数得到 secretBox，结果在进程内缓存。
+func deriveBox(passphrase string, k kdfParams) (*secretBox, error) {
+	cacheKey := k.String() + "\x00" + passphrase
+	boxMu.Lock()
+	defer boxMu.Unlock()
+	if box, ok := boxCache[cacheKey]; ok {
+		return box, nil
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), k.salt, k.n, k.r, k.p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	box := &secretBox{aead: aead}
+	boxCache[cacheKey] = box
+	return box, nil
+}
+
+func (b *secretBox) seal(plain string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *secretBox) open(stored string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, secretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("密文格式错误: %w", err)
+	}
+	if len(raw) < b.aead.NonceSize() {
+		return "", errors.New("密文长度错误")
+	}
+	nonce, ciphertext := raw[:b.aead.NonceSize()], raw[b.aead.NonceSize():]
+	plain, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrWrongMasterKey
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted 判断字段值是否为密文。
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// IsDatabaseEncrypted 判断数据库是否启用了静态加密。
+func IsDatabaseEncrypted() (bool, error) {
+	v, err := getMeta(metaSecretKDF)
+	if err != nil {
+		return false, err
+	}
+	return v != "", nil
+}
+
+// unlockBox 使用给定主密码打开数据库的 secretBox 并校验密码是否正确。
+func unlockBox(passphrase string) (*secretBox, error) {
+	kdf, err := getMeta(metaSecretKDF)
+	if err != nil {
+		return nil, err
+	}
+	if kdf == "" {
+		return nil, ErrNotEncrypted
+	}
+	if passphrase == "" {
+		return nil, ErrMasterKeyRequired
+	}
+	k, err := parseKDFParams(kdf)
+	if err != nil {
+		return nil, err
+	}
+	box, err := deriveBox(passphrase, k)
+	if err != nil {
+		return nil, err
+	}
+	check, err := getMeta(metaSecretCheck)
+	if err != nil {
+		return nil, err
+	}
+	if plain, err := box.open(check); err != nil || plain != secretCheckPlain {
+		return nil, ErrWrongMasterKey
+	}
+	return box, nil
+}
+
+// currentBox 返回当前数据库使用的 secretBox，数据库未加密时返回 nil。
+func currentBox() (*secretBox, error) {
+	encrypted, err := IsDatabaseEncrypted()
+	if err != nil || !encrypted {
+		return nil, err
+	}
+	passphrase, err := MasterPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	return unlockBox(passphrase)
+}
+
+// sealSecret 在数据库启用加密时加密敏感字段，否则原样返回。
+func sealSecret(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	box, err := currentBox()
+	if err != nil {
+		return "", err
+	}
+	if box == nil {
+		return plain, nil
+	}
+	return box.seal(plain)
+}
+
+// openSecret 解密敏感字段，明文值原样返回。解密结果只保存在内存中。
+func openSecret(stored string) (string, error) {
+	if !IsEncrypted(stored) {
+		return stored, nil
+	}
+	box, err := currentBox()
+	if err != nil {
+		return "", err
+	}
+	if box == nil {
+		return "", ErrMasterKeyRequired
+	}
+	return box.open(stored)
+}
+
+// rewriteSecrets 在事务中使用 fn 转换 users 表中所有的 password 与 token，以及通知渠道的参数。
+func rewriteSecrets(tx *sql.Tx, fn func(string) (string, error)) error {
+	rows, err := tx.Query(`SELECT account, IFNULL(password, ''), IFNULL(token, '') FROM users`)
+	if err != nil {
+		return fmt.Errorf("读取用户数据失败: %w", err)
+	}
+	type secretRow struct{ account, password, token string }
+	var all []secretRow
+	for rows.Next() {
+		var r secretRow
+		if err := rows.Scan(&r.account, &r.password, &r.token); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取用户数据失败: %w", err)
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range all {
+		password, err := fn(r.password)
+		if err != nil {
+			return fmt.Errorf("处理账号 %s 的密码失败: %w", r.account, err)
+		}
+		token, err := fn(r.token)
+		if err != nil {
+			return fmt.Errorf("处理账号 %s 的 token 失败: %w", r.account, err)
+		}
+		if _, err := tx.Exec(`UPDATE users SET password = ?, token = ? WHERE account = ?`, password, token, r.account); err != nil {
+			return fmt.Errorf("更新账号 %s 失败: %w", r.account, err)
+		}
+	}
+	return rewriteNotifySecrets(tx, fn)
+}
+
+// initSecrets 生成新的派生参数与校验值并写入 app_meta，返回对应的 secretBox。
+func initSecrets(tx *sql.Tx, passphrase string) (*secretBox, error) {
+	k, err := newKDFParams()
+	if err != nil {
+		return nil, err
+	}
+	box, err := deriveBox(passphrase, k)
+	if err != nil {
+		return nil, err
+	}
+	check, err := box.seal(secretCheckPlain)
+	if err != nil {
+		return nil, err
+	}
+	if err := setMetaTx(tx, metaSecretKDF, k.String()); err != nil {
+		return nil, err
+	}
+	if err := setMetaTx(tx, metaSecretCheck, check); err != nil {
+		return nil, err
+	}
+	return box, nil
+}
+
+// EncryptDatabase 启用静态加密，并加密所有已保存的密码与 token。
+func EncryptDatabase(passphrase string) error {
+	if passphrase == "" {
+		return ErrMasterKeyRequired
+	}
+	encrypted, err := IsDatabaseEncrypted()
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return ErrAlreadyEncrypted
+	}
+
+	return withTx(func(tx *sql.Tx) error {
+		box, err := initSecrets(tx, passphrase)
+		if err != nil {
+			return err
+		}
+		return rewriteSecrets(tx, func(v string) (string, error) {
+			if v == "" || IsEncrypted(v) {
+				return v, nil
+			}
+			return box.seal(v)
+		})
+	})
+}
+
+// DecryptDatabase 关闭静态加密，将所有密码与 token 还原为明文。
+func DecryptDatabase(passphrase string) error {
+	box, err := unlockBox(passphrase)
+	if err != nil {
+		return err
+	}
+	return withTx(func(tx *sql.Tx) error {
+		if err := rewriteSecrets(tx, func(v string) (string, error) {
+			if !IsEncrypted(v) {
+				return v, nil
+			}
+			return box.open(v)
+		}); err != nil {
+			return err
+		}
+		if err := deleteMetaTx(tx, metaSecretKDF); err != nil {
+			return err
+		}
+		return deleteMetaTx(tx, metaSecretCheck)
+	})
+}
+
+// RekeyDatabase 使用新的主密码（及新的盐）重新加密所有密码与 token。
+func RekeyDatabase(oldPassphrase, newPassphrase string) error {
+	if newPassphrase == "" {
+		return errors.New("新主密码不能为空")
+	}
+	oldBox, err := unlockBox(oldPassphrase)
+	if err != nil {
+		return err
+	}
+	return withTx(func(tx *sql.Tx) error {
+		newBox, err := initSecrets(tx, newPassphrase)
+		if err != nil {
+			return err
+		}
+		return rewriteSecrets(tx, func(v string) (string, error) {
+			if v == "" {
+				return v, nil
+			}
+			if IsEncrypted(v) {
+				plain, err := oldBox.open(v)
+				if err != nil {
+					return "", err
+				}
+				v = plain
+			}
+			return newBox.seal(v)
+		})
+	})
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB 在临时目录中初始化数据库，测试结束后关闭。
+func openTestDB(t *testing.T) {
+	t.Helper()
+	DBPath = filepath.Join(t.TempDir(), "config.db")
+	db = nil
+	require.NoError(t, InitDB())
+	t.Cleanup(func() {
+		CloseDB()
+		db = nil
+	})
+}
+
+func TestEncryptDatabase(t *testing.T) {
+	openTestDB(t)
+	t.Setenv(EnvMasterKey, "")
+	require.NoError(t, SaveUser("user", "secret", "tok", "", "", "", "", "", 7, "", "", "", ""))
+
+	require.NoError(t, EncryptDatabase("master"))
+
+	// 数据库中只保存密文
+	var storedPassword, storedToken string
+	require.NoError(t, db.QueryRow(`SELECT password, token FROM users WHERE account = 'user'`).Scan(&storedPassword, &storedToken))
+	assert.True(t, IsEncrypted(storedPassword))
+	assert.True(t, IsEncrypted(storedToken))
+
+	// 未提供主密码时无法读取
+	_, _, _, err := GetUser("user")
+	assert.ErrorIs(t, err, ErrMasterKeyRequired)
+
+	t.Setenv(EnvMasterKey, "wrong")
+	_, _, _, err = GetUser("user")
+	assert.ErrorIs(t, err, ErrWrongMasterKey)
+
+	t.Setenv(EnvMasterKey, "master")
+	token, _, _, err := GetUser("user")
+	require.NoError(t, err)
+	assert.Equal(t, "tok", token)
+
+	// 加密状态下写入的新 token 同样加密
+	require.NoError(t, UpdateToken("user", "tok2"))
+	require.NoError(t, db.QueryRow(`SELECT token FROM users WHERE account = 'user'`).Scan(&storedToken))
+	assert.True(t, IsEncrypted(storedToken))
+
+	require.NoError(t, RekeyDatabase("master", "master2"))
+	t.Setenv(EnvMasterKey, "master2")
+	password, _, err := GetCredentials("user")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", password)
+
+	require.NoError(t, DecryptDatabase("master2"))
+	require.NoError(t, db.QueryRow(`SELECT password, token FROM users WHERE account = 'user'`).Scan(&storedPassword, &storedToken))
+	assert.Equal(t, "secret", storedPassword)
+	assert.Equal(t, "tok2", storedToken)
+}
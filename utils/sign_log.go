@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 签到触发来源
+const (
+	SignTriggerManual   = "manual"
+	SignTriggerSchedule = "schedule"
+	SignTriggerAPI      = "api"
+	SignTriggerRetry    = "retry"
+)
+
+// 签到结果状态
+const (
+	SignStatusSuccess       = "success"
+	SignStatusFailed        = "failed"
+	SignStatusAlreadySigned = "already_signed" // 今日已签到，接口拒绝了重复签到
+)
+
+// signLogTimeLayout 是 sign_logs.signed_at 的存储格式（UTC），可直接按字符串比较。
+const signLogTimeLayout = "2006-01-02T15:04:05Z"
+
+// SignLog 是一次签到尝试的记录。
+type SignLog struct {
+	ID        int64
+	Account   string
+	SignedAt  time.Time
+	Latitude  string
+	Longitude string
+	Address   string
+	Status    string
+	Code      int
+	Message   string
+	ErrorKind string
+	Duration  time.Duration
+	Trigger   string
+}
+
+// SignLogFilter 是查询签到记录的过滤条件，零值字段不参与过滤。
+type SignLogFilter struct {
+	Account string
+	// From、To 为时间范围，左闭右开。
+	From   time.Time
+	To     time.Time
+	Status string
+	Limit  int
+}
+
+// RecordSignLog 保存一次签到尝试，返回记录 ID。
+func RecordSignLog(l SignLog) (int64, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return 0, err
+		}
+	}
+	if l.SignedAt.IsZero() {
+		l.SignedAt = time.Now()
+	}
+	res, err := db.Exec(`
+    INSERT INTO sign_logs (account, signed_at, latitude, longitude, address, status, code, message, error_kind, duration_ms, trigger_source)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.Account, l.SignedAt.UTC().Format(signLogTimeLayout), l.Latitude, l.Longitude, l.Address,
+		l.Status, l.Code, l.Message, l.ErrorKind, l.Duration.Milliseconds(), l.Trigger)
+	if err != nil {
+		return 0, fmt.Errorf("写入签到日志失败: %v", err)
+	}
+	return res.LastInsertId()
+}
+
+// QuerySignLogs 按条件查询签到记录，按时间倒序返回。
+func QuerySignLogs(f SignLogFilter) ([]SignLog, error) {
+	if db == nil {
+		if err := InitDB(); err != nil {
+			return nil, err
+		}
+	}
+
+	var where []string
+	var args []interface{}
+	if f.Account != "" {
+		where = append(where, "account = ?")
+		args = append(args, f.Account)
+	}
+	if !f.From.IsZero() {
+		where = append(where, "signed_at >= ?")
+		args = append(args, f.From.UTC().Format(signLogTimeLayout))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "signed_at < ?")
+		args = append(args, f.To.UTC().Format(signLogTimeLayout))
+	}
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, f.Status)
+	}
+
+	querySQL := `SELECT id, account, signed_at, IFNULL(latitude, ''), IFNULL(longitude, ''), IFNULL(address, ''),
+        status, IFNULL(code, 0), IFNULL(message, ''), IFNULL(error_kind, ''), IFNULL(duration_ms, 0), IFNULL(trigger_source, '')
+        FROM sign_logs`
+	if len(where) > 0 {
+		querySQL += " WHERE " + strings.Join(where, " AND ")
+	}
+	querySQL += " ORDER BY signed_at DESC, id DESC"
+	if f.Limit > 0 {
+		querySQL += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := db.Query(querySQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询签到日志失败: %v", err)
+	}
+	defer rows.Close()
+
+	var logs []SignLog
+	for rows.Next() {
+		var (
+			l          SignLog
+			signedAt   string
+			durationMs int64
+		)
+		if err := rows.Scan(&l.ID, &l.Account, &signedAt, &l.Latitude, &l.Longitude, &l.Address,
+			&l.Status, &l.Code, &l.Message, &l.ErrorKind, &durationMs, &l.Trigger); err != nil {
+			return nil, fmt.Errorf("读取签到日志失败: %v", err)
+		}
+		l.SignedAt, _ = time.Parse(signLogTimeLayout, signedAt)
+		l.Duration = time.Duration(durationMs) * time.Millisecond
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
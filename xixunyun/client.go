@@ -0,0 +1,257 @@
+// Package xixunyun 提供习讯云 API 的类型化客户端。
+//
+// 所有请求都经由 Client 发出：基础地址可配置，公共查询参数（from、version、
+// platform、entrance_year、graduate_year、school_id、token）与请求头统一在此注入，
+// 响应统一解码为带类型的结构体，而不是 map[string]interface{}。
+package xixunyun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL 是习讯云 API 的默认地址。
+const DefaultBaseURL = "https://api.xixunyun.com"
+
+// 习讯云 App 请求中携带的固定参数。
+const (
+	AppVersion   = "5.1.3"
+	AppPlatform  = "android"
+	AppUserAgent = "okhttp/3.8.0"
+	WebUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+	WebOrigin    = "https://www.xixunyun.com"
+)
+
+// CodeSuccess 是接口成功时返回的 code。
+const CodeSuccess = 20000
+
+// Client 是习讯云 API 客户端，零值不可用，请使用 NewClient 创建。
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Option 用于配置 Client。
+type Option func(*Client)
+
+// WithBaseURL 设置 API 基础地址，例如测试时指向 httptest 服务。
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithHTTPClient 设置底层使用的 http.Client。
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.httpClient = hc
+		}
+	}
+}
+
+// NewClient 创建一个新的客户端。
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		baseURL:    DefaultBaseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL 返回客户端使用的 API 基础地址。
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Identity 描述一次请求所属的用户，用于生成公共查询参数。
+type Identity struct {
+	Token        string
+	SchoolID     string
+	EntranceYear string
+	GraduateYear string
+}
+
+// query 生成 App 接口的公共查询参数。
+func (id Identity) query() url.Values {
+	q := url.Values{}
+	if id.Token != "" {
+		q.Set("token", id.Token)
+	}
+	q.Set("from", "app")
+	q.Set("version", AppVersion)
+	q.Set("platform", AppPlatform)
+	q.Set("entrance_year", defaultString(id.EntranceYear, "0"))
+	q.Set("graduate_year", defaultString(id.GraduateYear, "0"))
+	q.Set("school_id", id.SchoolID)
+	return q
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// envelope 是习讯云接口统一的响应外层结构。
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// appRequest 构造一个 App 端接口请求，body 为 nil 时发送 GET。
+//
+// 注意：不手动设置 Accept-Encoding，交由 net/http 自动处理 gzip 解压。
+func (c *Client) appRequest(ctx context.Context, path string, id Identity, extra url.Values, form url.Values) (*http.Request, error) {
+	q := id.query()
+	for k, vs := range extra {
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+	u := c.baseURL + path + "?" + q.Encode()
+
+	method := http.MethodGet
+	var body io.Reader
+	if form != nil {
+		method = http.MethodPost
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", AppUserAgent)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return req, nil
+}
+
+// webRequest 构造一个网页端接口请求（文件上传、报告等），仅携带 token 查询参数。
+func (c *Client) webRequest(ctx context.Context, path, token, contentType string, body io.Reader) (*http.Request, error) {
+	u := c.baseURL + path + "?" + url.Values{"token": {token}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", WebUserAgent)
+	req.Header.Set("Accept", "application/json, text/javascript, */*; q=0.01")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Origin", WebOrigin)
+	req.Header.Set("Referer", WebOrigin+"/")
+	req.Header.Set("sec-ch-ua", `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`)
+	req.Header.Set("sec-ch-ua-mobile", "?0")
+	req.Header.Set("sec-ch-ua-platform", `"Windows"`)
+	req.Header.Set("sec-fetch-dest", "empty")
+	req.Header.Set("sec-fetch-mode", "cors")
+	req.Header.Set("sec-fetch-site", "same-site")
+	return req, nil
+}
+
+// do 发送请求并解码响应。code 不为 CodeSuccess 时返回 *APIError，
+// out 不为 nil 时将 data 字段解码到 out。
+func (c *Client) do(req *http.Request, out interface{}) (*envelope, error) {
+	env, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if env.Code != CodeSuccess {
+		return env, newAPIError(env.Code, env.Message)
+	}
+	if err := env.decode(out); err != nil {
+		return env, err
+	}
+	return env, nil
+}
+
+// send 发送请求并解码响应外层结构，不检查 code。
+func (c *Client) send(req *http.Request) (*envelope, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+
+	var env envelope
+	decodeErr := json.Unmarshal(body, &env)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		httpErr := &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+		if decodeErr == nil {
+			httpErr.Code, httpErr.Message = env.Code, env.Message
+		}
+		return nil, httpErr
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("解析响应失败(HTTP %d): %w", resp.StatusCode, decodeErr)
+	}
+	return &env, nil
+}
+
+// decode 将 data 字段解码到 out，out 为 nil 或 data 为空时不做任何事。
+func (env *envelope) decode(out interface{}) error {
+	if out == nil || len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("解析响应数据失败: %w", err)
+	}
+	return nil
+}
+
+// FlexString 兼容接口中时而为字符串、时而为数字的字段。
+type FlexString string
+
+// UnmarshalJSON 实现 json.Unmarshaler。
+func (s *FlexString) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*s = ""
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var v string
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		*s = FlexString(v)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("无法解析为字符串或数字: %s", string(b))
+	}
+	*s = FlexString(n.String())
+	return nil
+}
+
+// String 返回字符串值。
+func (s FlexString) String() string {
+	return string(s)
+}
+
+// Float64 将值解析为浮点数，无法解析时返回 0。
+func (s FlexString) Float64() float64 {
+	f, _ := strconv.ParseFloat(string(s), 64)
+	return f
+}
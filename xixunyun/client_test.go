@@ -0,0 +1,323 @@
+package xixunyun_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"xixunyunsign/xixunyun"
+)
+
+func TestLogin(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/login/api", r.URL.Path)
+		assert.Equal(t, "app", r.URL.Query().Get("from"))
+		assert.Equal(t, xixunyun.AppVersion, r.URL.Query().Get("version"))
+		assert.Equal(t, "7", r.URL.Query().Get("school_id"))
+		assert.Empty(t, r.URL.Query().Get("token"))
+		assert.Equal(t, xixunyun.AppUserAgent, r.Header.Get("User-Agent"))
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "user", r.PostForm.Get("account"))
+		assert.Equal(t, "pass", r.PostForm.Get("password"))
+
+		w.Write([]byte(`{"code":20000,"message":"ok","data":{"token":"tok","user_name":"张三","school_id":7,"entrance_year":2022}}`))
+	}))
+	defer ts.Close()
+
+	c := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+	resp, err := c.Login(context.Background(), xixunyun.LoginRequest{Account: "user", Password: "pass", SchoolID: "7"})
+	require.NoError(t, err)
+	assert.Equal(t, "tok", resp.Token)
+	assert.Equal(t, "张三", resp.UserName.String())
+	assert.Equal(t, float64(7), resp.SchoolID.Float64())
+	assert.Equal(t, "2022", resp.EntranceYear.String())
+}
+
+func TestLogin_APIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":40000,"message":"账号或密码错误"}`))
+	}))
+	defer ts.Close()
+
+	c := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+	_, err := c.Login(context.Background(), xixunyun.LoginRequest{Account: "user", Password: "bad"})
+
+	var apiErr *xixunyun.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 40000, apiErr.Code)
+	assert.Equal(t, "账号或密码错误", apiErr.Message)
+}
+
+func TestSignIn(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/signin_rsa", r.URL.Path)
+		assert.Equal(t, "tok", r.URL.Query().Get("token"))
+		assert.Equal(t, "2022", r.URL.Query().Get("entrance_year"))
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "江苏省南京市玄武区", r.PostForm.Get("address"))
+		// 经纬度必须以密文提交
+		assert.NotEqual(t, "32.05", r.PostForm.Get("latitude"))
+		assert.NotEmpty(t, r.PostForm.Get("longitude"))
+
+		w.Write([]byte(`{"code":20000,"message":"签到成功","data":{}}`))
+	}))
+	defer ts.Close()
+
+	c := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+	id := xixunyun.Identity{Token: "tok", SchoolID: "7", EntranceYear: "2022", GraduateYear: "2025"}
+	resp, err := c.SignIn(context.Background(), id, xixunyun.SignInRequest{
+		Address:   "江苏省南京市玄武区",
+		Latitude:  "32.05",
+		Longitude: "118.79",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "签到成功", resp.Message)
+}
+
+func TestUploadFile(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/file/form?token=tok", r.URL.String())
+		assert.Equal(t, "tok", r.Header.Get("Authorization"))
+		f, _, err := r.FormFile("addFile")
+		require.NoError(t, err)
+		defer f.Close()
+		assert.Equal(t, "report", r.FormValue("business"))
+
+		w.Write([]byte(`{"code":20000,"data":{"uri":"https://example.com/a.jpg"}}`))
+	}))
+	defer ts.Close()
+
+	c := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+	resp, err := c.UploadFile(context.Background(), "tok", xixunyun.UploadRequest{FileName: "a.jpg", File: strings.NewReader("img")})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a.jpg", resp.URI)
+}
+
+func TestSchoolMap(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/login/schoolmap", r.URL.Path)
+		w.Write([]byte(`{"code":20000,"data":[{"name":"南京","id":320100,"list":[{"school_id":"7","school_name":"示例职业技术学院"}]}]}`))
+	}))
+	defer ts.Close()
+
+	c := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+	cities, err := c.SchoolMap(context.Background())
+	require.NoError(t, err)
+	require.Len(t, cities, 1)
+	assert.Equal(t, "320100", cities[0].CityID.String())
+	assert.Equal(t, "7", cities[0].Schools[0].SchoolID.String())
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		code    int
+		message string
+		kind    xixunyun.ErrorKind
+	}{
+		{40001, "登录失效，请重新登录", xixunyun.KindTokenExpired},
+		{40001, "", xixunyun.KindTokenExpired},
+		{40000, "登录已过期，请重新登录", xixunyun.KindTokenExpired},
+		{40000, "Token 已过期", xixunyun.KindTokenExpired},
+		{40000, "账号或密码错误", xixunyun.KindWrongPassword},
+		{40000, "账号或密码错误，请重新登录", xixunyun.KindWrongPassword},
+		{40000, "今日已签到", xixunyun.KindAlreadySigned},
+		{40000, "当前位置不在签到范围内", xixunyun.KindOutOfRange},
+		{50000, "系统维护中", xixunyun.KindMaintenance},
+		{40000, "操作过于频繁，请稍后再试", xixunyun.KindRateLimited},
+		// 包含关键字但与登录状态无关的消息不应触发重新登录
+		{40000, "设备token获取失败", xixunyun.KindUnknown},
+		{40000, "修改手机号后需重新登录", xixunyun.KindUnknown},
+		{40000, "已签到人数已满", xixunyun.KindUnknown},
+		{40000, "奇怪的错误", xixunyun.KindUnknown},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.kind, xixunyun.Classify(tc.code, tc.message), "%d %s", tc.code, tc.message)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	cases := []struct {
+		body     string
+		sentinel error
+		kind     xixunyun.ErrorKind
+	}{
+		{`{"code":40001,"message":"登录失效，请重新登录"}`, xixunyun.ErrTokenExpired, xixunyun.KindTokenExpired},
+		{`{"code":40000,"message":"账号或密码错误"}`, xixunyun.ErrWrongPassword, xixunyun.KindWrongPassword},
+		{`{"code":40000,"message":"今日已签到"}`, xixunyun.ErrAlreadySigned, xixunyun.KindAlreadySigned},
+		{`{"code":40000,"message":"当前位置不在签到范围内"}`, xixunyun.ErrOutOfRange, xixunyun.KindOutOfRange},
+		{`{"code":50000,"message":"系统维护中"}`, xixunyun.ErrMaintenance, xixunyun.KindMaintenance},
+		{`{"message":"操作过于频繁"}`, xixunyun.ErrRateLimited, xixunyun.KindRateLimited},
+		{`{"code":40000,"message":"奇怪的错误"}`, xixunyun.ErrUnknown, xixunyun.KindUnknown},
+	}
+	for _, tc := range cases {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tc.body))
+		}))
+		c := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+		_, err := c.Homepage(context.Background(), xixunyun.Identity{Token: "tok"}, xixunyun.HomepageRequest{})
+		ts.Close()
+
+		assert.ErrorIs(t, err, tc.sentinel, tc.body)
+		assert.Equal(t, tc.kind, xixunyun.KindOf(err), tc.body)
+	}
+}
+
+func TestKindOfJoinedErrors(t *testing.T) {
+	err := errors.Join(xixunyun.ErrRateLimited, xixunyun.ErrTokenExpired)
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, xixunyun.KindTokenExpired, xixunyun.KindOf(err))
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("<html>503</html>"))
+	}))
+	defer ts.Close()
+
+	c := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+	_, err := c.Homepage(context.Background(), xixunyun.Identity{Token: "tok"}, xixunyun.HomepageRequest{})
+
+	var httpErr *xixunyun.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+	assert.ErrorIs(t, err, xixunyun.ErrMaintenance)
+}
+
+func TestHTTPErrorKeepsMessage(t *testing.T) {
+	cases := []struct {
+		status   int
+		body     string
+		sentinel error
+		kind     xixunyun.ErrorKind
+	}{
+		{http.StatusServiceUnavailable, `{"code":50000,"message":"系统维护中"}`, xixunyun.ErrMaintenance, xixunyun.KindMaintenance},
+		{http.StatusBadRequest, `{"code":40000,"message":"不在签到范围内"}`, xixunyun.ErrOutOfRange, xixunyun.KindOutOfRange},
+		{http.StatusInternalServerError, `{"code":20000,"message":"ok"}`, nil, xixunyun.KindUnknown},
+	}
+	for _, tc := range cases {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+			w.Write([]byte(tc.body))
+		}))
+		c := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+		_, err := c.Homepage(context.Background(), xixunyun.Identity{Token: "tok"}, xixunyun.HomepageRequest{})
+		ts.Close()
+
+		var httpErr *xixunyun.HTTPError
+		require.True(t, errors.As(err, &httpErr), tc.body)
+		assert.Equal(t, tc.status, httpErr.StatusCode, tc.body)
+		if tc.sentinel != nil {
+			assert.ErrorIs(t, err, tc.sentinel, tc.body)
+			assert.Contains(t, err.Error(), httpErr.Message, tc.body)
+		}
+		assert.Equal(t, tc.kind, xixunyun.KindOf(err), tc.body)
+	}
+}
+
+// memoryStore 是测试用的 CredentialStore
+type memoryStore struct {
+	token    string
+	password string
+	saved    int
+}
+
+func (m *memoryStore) Identity(account string) (xixunyun.Identity, error) {
+	return xixunyun.Identity{Token: m.token, SchoolID: "7"}, nil
+}
+
+func (m *memoryStore) Credentials(account string) (xixunyun.LoginRequest, error) {
+	return xixunyun.LoginRequest{Account: account, Password: m.password, SchoolID: "7"}, nil
+}
+
+func (m *memoryStore) SaveToken(account string, resp *xixunyun.LoginResponse) error {
+	m.token = resp.Token
+	m.saved++
+	return nil
+}
+
+func TestSession_Relogin(t *testing.T) {
+	logins := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login/api":
+			logins++
+			w.Write([]byte(`{"code":20000,"data":{"token":"new"}}`))
+		case "/signin40/homepage":
+			if r.URL.Query().Get("token") != "new" {
+				w.Write([]byte(`{"code":40001,"message":"登录失效，请重新登录"}`))
+				return
+			}
+			w.Write([]byte(`{"code":20000,"data":{"sign_resources_info":{"mid_sign_latitude":"32.05","mid_sign_longitude":118.79}}}`))
+		}
+	}))
+	defer ts.Close()
+
+	store := &memoryStore{token: "old", password: "pass"}
+	s := xixunyun.NewSession(xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL)), store, "user")
+	resp, err := s.Homepage(context.Background(), xixunyun.HomepageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "118.79", resp.SignResourcesInfo.MidSignLongitude.String())
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, "new", store.token)
+	assert.Equal(t, 1, store.saved)
+}
+
+func TestSession_EmptyToken(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/login/api":
+			w.Write([]byte(`{"code":20000,"data":{"token":"new"}}`))
+		case "/signin40/homepage":
+			assert.Equal(t, "new", r.URL.Query().Get("token"))
+			w.Write([]byte(`{"code":20000,"data":{}}`))
+		}
+	}))
+	defer ts.Close()
+	client := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+
+	// token 为空时不带 token 请求，直接使用保存的密码重新登录
+	store := &memoryStore{password: "pass"}
+	_, err := xixunyun.NewSession(client, store, "user").Homepage(context.Background(), xixunyun.HomepageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/login/api", "/signin40/homepage"}, paths)
+	assert.Equal(t, "new", store.token)
+
+	// 未保存密码
+	paths = nil
+	_, err = xixunyun.NewSession(client, &memoryStore{}, "user").Homepage(context.Background(), xixunyun.HomepageRequest{})
+	assert.ErrorIs(t, err, xixunyun.ErrTokenExpired)
+	assert.ErrorIs(t, err, xixunyun.ErrNoCredentials)
+	assert.Empty(t, paths)
+}
+
+func TestSession_NoRelogin(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEqual(t, "/login/api", r.URL.Path)
+		w.Write([]byte(`{"code":40001,"message":"登录失效，请重新登录"}`))
+	}))
+	defer ts.Close()
+
+	client := xixunyun.NewClient(xixunyun.WithBaseURL(ts.URL))
+
+	// 关闭自动重新登录
+	s := xixunyun.NewSession(client, &memoryStore{token: "old", password: "pass"}, "user")
+	s.AutoRelogin = false
+	_, err := s.Homepage(context.Background(), xixunyun.HomepageRequest{})
+	assert.ErrorIs(t, err, xixunyun.ErrTokenExpired)
+
+	// 未保存密码
+	s = xixunyun.NewSession(client, &memoryStore{token: "old"}, "user")
+	_, err = s.Homepage(context.Background(), xixunyun.HomepageRequest{})
+	assert.ErrorIs(t, err, xixunyun.ErrTokenExpired)
+	assert.ErrorIs(t, err, xixunyun.ErrNoCredentials)
+}
@@ -0,0 +1,173 @@
+package xixunyun
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorKind 是对接口错误的分类。
+type ErrorKind string
+
+// 接口错误分类。
+const (
+	KindUnknown        ErrorKind = "unknown"
+	KindTokenExpired   ErrorKind = "token_expired"
+	KindWrongPassword  ErrorKind = "wrong_password"
+	KindSchoolMismatch ErrorKind = "school_mismatch"
+	KindAlreadySigned  ErrorKind = "already_signed"
+	KindOutOfRange     ErrorKind = "out_of_range"
+	KindMaintenance    ErrorKind = "maintenance"
+	KindRateLimited    ErrorKind = "rate_limited"
+)
+
+// 与错误分类一一对应的哨兵错误，调用方可使用 errors.Is 判断，
+// 例如 errors.Is(err, xixunyun.ErrTokenExpired)。
+var (
+	ErrUnknown        = errors.New("未知错误")
+	ErrTokenExpired   = errors.New("登录状态已失效")
+	ErrWrongPassword  = errors.New("账号或密码错误")
+	ErrSchoolMismatch = errors.New("学校与账号不匹配")
+	ErrAlreadySigned  = errors.New("今日已签到")
+	ErrOutOfRange     = errors.New("不在签到范围内")
+	ErrMaintenance    = errors.New("服务器维护中")
+	ErrRateLimited    = errors.New("请求过于频繁")
+)
+
+// kindErrors 按固定顺序列出分类与哨兵错误，KindOf 依次匹配，
+// 错误链同时包含多个哨兵错误时结果保持确定。
+var kindErrors = []struct {
+	kind ErrorKind
+	err  error
+}{
+	{KindUnknown, ErrUnknown},
+	{KindTokenExpired, ErrTokenExpired},
+	{KindWrongPassword, ErrWrongPassword},
+	{KindSchoolMismatch, ErrSchoolMismatch},
+	{KindAlreadySigned, ErrAlreadySigned},
+	{KindOutOfRange, ErrOutOfRange},
+	{KindMaintenance, ErrMaintenance},
+	{KindRateLimited, ErrRateLimited},
+}
+
+// Err 返回分类对应的哨兵错误。
+func (k ErrorKind) Err() error {
+	for _, ke := range kindErrors {
+		if ke.kind == k {
+			return ke.err
+		}
+	}
+	return ErrUnknown
+}
+
+// codeKinds 是含义确定的 code。40001 在所有接口上都表示 token 无效或已过期；
+// 其他业务错误统一返回 40000，需要再根据 message 区分。
+var codeKinds = map[int]ErrorKind{
+	40001: KindTokenExpired,
+}
+
+// messageRules 按顺序匹配接口返回的 message，只使用完整的短语，
+// 避免“设备token获取失败”之类无关的消息被误判为登录失效而触发重新登录。
+var messageRules = []struct {
+	kind    ErrorKind
+	phrases []string
+}{
+	{KindTokenExpired, []string{"登录失效", "登录已失效", "登录过期", "登录已过期", "未登录", "会话已失效",
+		"token失效", "token已失效", "token过期", "token已过期", "token无效", "无效的token"}},
+	{KindWrongPassword, []string{"密码错误", "密码不正确", "账号或密码", "账号不存在", "用户不存在"}},
+	{KindSchoolMismatch, []string{"学校不匹配", "学校不存在", "学校错误", "不属于该学校", "学校信息有误"}},
+	{KindAlreadySigned, []string{"今日已签到", "今天已签到", "已经签到", "重复签到"}},
+	{KindOutOfRange, []string{"不在签到范围", "超出签到范围", "不在范围内", "超出范围", "距离过远", "不在签到时间"}},
+	{KindMaintenance, []string{"维护中", "系统维护", "系统升级", "服务暂停"}},
+	{KindRateLimited, []string{"过于频繁", "请求过多", "请稍后再试"}},
+}
+
+// Classify 根据接口返回的 code 与 message 判断错误分类：先匹配含义确定的 code，
+// 再按 message 中的短语判断。
+func Classify(code int, message string) ErrorKind {
+	if kind, ok := codeKinds[code]; ok {
+		return kind
+	}
+	lower := strings.ToLower(strings.ReplaceAll(message, " ", ""))
+	for _, rule := range messageRules {
+		for _, phrase := range rule.phrases {
+			if strings.Contains(lower, phrase) {
+				return rule.kind
+			}
+		}
+	}
+	return KindUnknown
+}
+
+// APIError 表示接口返回了非成功的 code。
+type APIError struct {
+	Code    int
+	Message string
+	Kind    ErrorKind
+}
+
+func newAPIError(code int, message string) *APIError {
+	return &APIError{Code: code, Message: message, Kind: Classify(code, message)}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("习讯云接口返回错误(code=%d): %s", e.Code, e.Message)
+}
+
+// Unwrap 返回错误分类对应的哨兵错误，使 errors.Is 可以直接判断分类。
+func (e *APIError) Unwrap() error {
+	return e.Kind.Err()
+}
+
+// HTTPError 表示接口返回了非 2xx 响应。响应体是接口的 JSON 结构时，
+// Code 与 Message 保存其中的 code 与 message。
+type HTTPError struct {
+	StatusCode int
+	Body       string
+	Code       int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("习讯云接口 HTTP 状态异常: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap 将部分 HTTP 状态码映射为对应的错误分类；其他状态码按响应中的
+// code 与 message 分类。
+func (e *HTTPError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrTokenExpired
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusServiceUnavailable:
+		return ErrMaintenance
+	}
+	if kind := Classify(e.Code, e.Message); kind != KindUnknown {
+		return kind.Err()
+	}
+	return nil
+}
+
+// KindOf 返回错误链中的接口错误分类，err 不是接口错误时返回空字符串。
+func KindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	for _, ke := range kindErrors {
+		if ke.kind != KindUnknown && errors.Is(err, ke.err) {
+			return ke.kind
+		}
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return KindUnknown
+	}
+	return ""
+}
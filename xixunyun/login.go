@@ -0,0 +1,54 @@
+package xixunyun
+
+import (
+	"context"
+	"net/url"
+)
+
+// LoginRequest 是登录接口的请求参数。
+type LoginRequest struct {
+	Account  string
+	Password string
+	SchoolID string
+}
+
+// LoginResponse 是登录接口返回的用户信息。
+type LoginResponse struct {
+	Token          string     `json:"token"`
+	BindPhone      FlexString `json:"bind_phone"`
+	UserNumber     FlexString `json:"user_number"`
+	UserName       FlexString `json:"user_name"`
+	SchoolID       FlexString `json:"school_id"`
+	Sex            FlexString `json:"sex"`
+	ClassName      FlexString `json:"class_name"`
+	EntranceYear   FlexString `json:"entrance_year"`
+	GraduationYear FlexString `json:"graduation_year"`
+}
+
+// Login 使用账号密码登录，返回 token 及用户信息。
+func (c *Client) Login(ctx context.Context, r LoginRequest) (*LoginResponse, error) {
+	form := url.Values{}
+	form.Set("app_version", AppVersion)
+	form.Set("registration_id", "")
+	form.Set("uuid", "fd9dc13a49cc850c")
+	form.Set("request_source", "3")
+	form.Set("platform", "2")
+	form.Set("mac", "7C:F3:1B:BB:F1:C4")
+	form.Set("password", r.Password)
+	form.Set("system", "10")
+	form.Set("school_id", r.SchoolID)
+	form.Set("model", "LM-G820")
+	form.Set("app_id", "cn.vanber.xixunyun.saas")
+	form.Set("account", r.Account)
+	form.Set("key", "")
+
+	req, err := c.appRequest(ctx, "/login/api", Identity{SchoolID: r.SchoolID}, nil, form)
+	if err != nil {
+		return nil, err
+	}
+	var out LoginResponse
+	if _, err := c.do(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,148 @@
+package xixunyun
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"strconv"
+)
+
+// UploadRequest 是文件上传接口的请求参数。
+type UploadRequest struct {
+	// FileName 上传时使用的文件名。
+	FileName string
+	// File 文件内容。
+	File io.Reader
+	// Business 文件所属业务，为空时为 report。
+	Business string
+}
+
+// UploadResponse 是文件上传接口的返回数据。
+type UploadResponse struct {
+	URI string `json:"uri"`
+}
+
+// UploadFile 上传附件（如月报图片），返回服务器上的文件地址。
+func (c *Client) UploadFile(ctx context.Context, token string, r UploadRequest) (*UploadResponse, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("addFile", r.FileName)
+	if err != nil {
+		return nil, fmt.Errorf("创建表单文件失败: %w", err)
+	}
+	if _, err := io.Copy(part, r.File); err != nil {
+		return nil, fmt.Errorf("写入文件内容失败: %w", err)
+	}
+	if err := writer.WriteField("input_name", "addFile"); err != nil {
+		return nil, fmt.Errorf("写入 input_name 字段失败: %w", err)
+	}
+	if err := writer.WriteField("business", defaultString(r.Business, "report")); err != nil {
+		return nil, fmt.Errorf("写入 business 字段失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("关闭表单失败: %w", err)
+	}
+
+	req, err := c.webRequest(ctx, "/file/form", token, writer.FormDataContentType(), body)
+	if err != nil {
+		return nil, err
+	}
+	// 上传接口以是否返回 uri 判断成功与否，不依赖 code。
+	env, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	var out UploadResponse
+	if err := env.decode(&out); err != nil || out.URI == "" {
+		return nil, newAPIError(env.Code, env.Message)
+	}
+	return &out, nil
+}
+
+// ReportRequest 是提交实习报告（日报/周报/月报）的请求参数。
+type ReportRequest struct {
+	// BusinessType 报告类型，如 day、week、month。
+	BusinessType string
+	// StartDate、EndDate 格式为 2006/01/02。
+	StartDate string
+	EndDate   string
+	// Content 报告内容，为接口要求的 JSON 数组字符串。
+	Content string
+	// Attachment 附件地址，通常为 UploadFile 返回的 URI。
+	Attachment string
+}
+
+// ReportResponse 是提交报告接口的返回结果。
+type ReportResponse struct {
+	Message string
+}
+
+// SubmitReport 提交实习报告。
+func (c *Client) SubmitReport(ctx context.Context, token string, r ReportRequest) (*ReportResponse, error) {
+	form := url.Values{}
+	form.Set("business_type", r.BusinessType)
+	form.Set("start_date", r.StartDate)
+	form.Set("end_date", r.EndDate)
+	form.Set("content", r.Content)
+	form.Set("attachment", r.Attachment)
+
+	req, err := c.webRequest(ctx, "/Reports/StudentOperator", token, "application/x-www-form-urlencoded; charset=UTF-8", bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	env, err := c.do(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ReportResponse{Message: env.Message}, nil
+}
+
+// ReportListRequest 是查询已提交报告的请求参数。
+type ReportListRequest struct {
+	// BusinessType 报告类型，为空时查询全部类型。
+	BusinessType string
+	// Page 从 1 开始，Size 为每页条数，为 0 时使用接口默认值。
+	Page int
+	Size int
+}
+
+// Report 是一份已提交的报告及其批阅情况。
+type Report struct {
+	ID           FlexString `json:"id"`
+	BusinessType string     `json:"business_type"`
+	StartDate    string     `json:"start_date"`
+	EndDate      string     `json:"end_date"`
+	// Comment 为老师的批阅内容，未批阅时为空；Commenter 为批阅人。
+	Comment   string `json:"comment"`
+	Commenter string `json:"comment_name"`
+}
+
+// ListReports 查询已提交的报告及老师的批阅，按提交时间倒序返回。
+func (c *Client) ListReports(ctx context.Context, token string, r ReportListRequest) ([]Report, error) {
+	form := url.Values{}
+	if r.BusinessType != "" {
+		form.Set("business_type", r.BusinessType)
+	}
+	if r.Page > 0 {
+		form.Set("page_no", strconv.Itoa(r.Page))
+	}
+	if r.Size > 0 {
+		form.Set("page_size", strconv.Itoa(r.Size))
+	}
+
+	req, err := c.webRequest(ctx, "/Reports/StudentList", token, "application/x-www-form-urlencoded; charset=UTF-8", bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		List []Report `json:"list"`
+	}
+	if _, err := c.do(req, &out); err != nil {
+		return nil, err
+	}
+	return out.List, nil
+}
@@ -0,0 +1,43 @@
+package xixunyun
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// School 描述一所学校。
+type School struct {
+	SchoolID   FlexString `json:"school_id"`
+	SchoolName string     `json:"school_name"`
+}
+
+// SchoolCity 是按城市分组的学校列表。
+type SchoolCity struct {
+	CityName string     `json:"name"`
+	CityID   FlexString `json:"id"`
+	Schools  []School   `json:"list"`
+}
+
+// SchoolMap 获取全部学校列表，用于通过学校名称查询学校 ID。
+func (c *Client) SchoolMap(ctx context.Context) ([]SchoolCity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/login/schoolmap", nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", AppUserAgent)
+
+	// 学校列表接口以 data 是否有内容判断成功与否。
+	env, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	var out []SchoolCity
+	if err := env.decode(&out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 && env.Code != CodeSuccess {
+		return nil, newAPIError(env.Code, env.Message)
+	}
+	return out, nil
+}
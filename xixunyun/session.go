@@ -0,0 +1,132 @@
+package xixunyun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CredentialStore 为 Session 提供账号的请求身份与登录凭据，并持久化新的 token。
+type CredentialStore interface {
+	// Identity 返回账号当前的请求身份（含 token）。
+	Identity(account string) (Identity, error)
+	// Credentials 返回重新登录所需的凭据，未保存密码时 Password 为空。
+	Credentials(account string) (LoginRequest, error)
+	// SaveToken 持久化重新登录后获得的 token。
+	SaveToken(account string, resp *LoginResponse) error
+}
+
+// ErrNoCredentials 表示 token 已失效且没有可用于重新登录的密码。
+var ErrNoCredentials = errors.New("未保存密码，无法自动重新登录")
+
+// Session 绑定一个账号，在 token 失效时使用已保存的凭据自动重新登录，
+// 并透明地重试原请求一次。
+type Session struct {
+	Client  *Client
+	Store   CredentialStore
+	Account string
+	// AutoRelogin 为 false 时不尝试重新登录，直接返回 token 失效错误。
+	AutoRelogin bool
+	// OnRelogin 在重新登录成功后调用，可为 nil。
+	OnRelogin func(account string)
+}
+
+// NewSession 创建一个默认开启自动重新登录的会话。
+func NewSession(client *Client, store CredentialStore, account string) *Session {
+	return &Session{Client: client, Store: store, Account: account, AutoRelogin: true}
+}
+
+// Do 使用账号当前身份执行 fn。若 fn 返回 ErrTokenExpired 且允许自动重新登录，
+// 则重新登录、保存新 token 并重试一次。token 为空(未保存或已被清除)时直接重新登录后执行。
+func (s *Session) Do(ctx context.Context, fn func(id Identity) error) error {
+	id, err := s.Store.Identity(s.Account)
+	if err != nil {
+		return err
+	}
+	if id.Token == "" {
+		err := fmt.Errorf("账号 %s 的 token 为空: %w", s.Account, ErrTokenExpired)
+		if !s.AutoRelogin {
+			return err
+		}
+		id, reloginErr := s.Relogin(ctx)
+		if reloginErr != nil {
+			return fmt.Errorf("%w (自动重新登录失败: %w)", err, reloginErr)
+		}
+		return fn(id)
+	}
+	err = fn(id)
+	if err == nil || !s.AutoRelogin || !errors.Is(err, ErrTokenExpired) {
+		return err
+	}
+
+	id, reloginErr := s.Relogin(ctx)
+	if reloginErr != nil {
+		return fmt.Errorf("%w (自动重新登录失败: %w)", err, reloginErr)
+	}
+	return fn(id)
+}
+
+// Relogin 使用已保存的凭据重新登录，保存并返回新的请求身份。
+func (s *Session) Relogin(ctx context.Context) (Identity, error) {
+	creds, err := s.Store.Credentials(s.Account)
+	if err != nil {
+		return Identity{}, err
+	}
+	if creds.Password == "" {
+		return Identity{}, ErrNoCredentials
+	}
+	if creds.Account == "" {
+		creds.Account = s.Account
+	}
+	resp, err := s.Client.Login(ctx, creds)
+	if err != nil {
+		return Identity{}, err
+	}
+	if err := s.Store.SaveToken(s.Account, resp); err != nil {
+		return Identity{}, fmt.Errorf("保存新 token 失败: %w", err)
+	}
+	if s.OnRelogin != nil {
+		s.OnRelogin(s.Account)
+	}
+	return s.Store.Identity(s.Account)
+}
+
+// Homepage 通过会话查询签到首页信息。
+func (s *Session) Homepage(ctx context.Context, r HomepageRequest) (*HomepageResponse, error) {
+	var resp *HomepageResponse
+	err := s.Do(ctx, func(id Identity) (err error) {
+		resp, err = s.Client.Homepage(ctx, id, r)
+		return err
+	})
+	return resp, err
+}
+
+// SignIn 通过会话执行签到。
+func (s *Session) SignIn(ctx context.Context, r SignInRequest) (*SignInResponse, error) {
+	var resp *SignInResponse
+	err := s.Do(ctx, func(id Identity) (err error) {
+		resp, err = s.Client.SignIn(ctx, id, r)
+		return err
+	})
+	return resp, err
+}
+
+// ListReports 通过会话查询已提交的报告及批阅。
+func (s *Session) ListReports(ctx context.Context, r ReportListRequest) ([]Report, error) {
+	var reports []Report
+	err := s.Do(ctx, func(id Identity) (err error) {
+		reports, err = s.Client.ListReports(ctx, id.Token, r)
+		return err
+	})
+	return reports, err
+}
+
+// SubmitReport 通过会话提交实习报告。
+func (s *Session) SubmitReport(ctx context.Context, r ReportRequest) (*ReportResponse, error) {
+	var resp *ReportResponse
+	err := s.Do(ctx, func(id Identity) (err error) {
+		resp, err = s.Client.SubmitReport(ctx, id.Token, r)
+		return err
+	})
+	return resp, err
+}
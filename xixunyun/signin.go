@@ -0,0 +1,135 @@
+package xixunyun
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// signPublicKey 是 signin_rsa 接口加密经纬度使用的公钥。
+const signPublicKey = `-----BEGIN PUBLIC KEY-----
+MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQDlYsiV3DsG+t8OFMLyhdmG2P2J
+4GJwmwb1rKKcDZmTxEphPiYTeFIg4IFEiqDCATAPHs8UHypphZTK6LlzANyTzl9L
+jQS6BYVQk81LhQ29dxyrXgwkRw9RdWaMPtcXRD4h6ovx6FQjwQlBM5vaHaJOHhEo
+rHOSyd/deTvcS+hRSQIDAQAB
+-----END PUBLIC KEY-----`
+
+// HomepageRequest 是签到首页接口的请求参数。
+type HomepageRequest struct {
+	// MonthDate 查询的月份，格式为 2006-01，为空时使用当前月份。
+	MonthDate string
+}
+
+// SignResourcesInfo 描述应签到的位置信息。
+type SignResourcesInfo struct {
+	MidSignLatitude  FlexString `json:"mid_sign_latitude"`
+	MidSignLongitude FlexString `json:"mid_sign_longitude"`
+}
+
+// HomepageResponse 是签到首页接口的返回数据。
+type HomepageResponse struct {
+	SignResourcesInfo SignResourcesInfo `json:"sign_resources_info"`
+	// Raw 保留完整的 data 字段，便于调用方读取未建模的内容。
+	Raw json.RawMessage `json:"-"`
+}
+
+// Homepage 查询签到首页信息，其中包含应签到位置的经纬度。
+func (c *Client) Homepage(ctx context.Context, id Identity, r HomepageRequest) (*HomepageResponse, error) {
+	monthDate := r.MonthDate
+	if monthDate == "" {
+		monthDate = time.Now().Format("2006-01")
+	}
+	req, err := c.appRequest(ctx, "/signin40/homepage", id, url.Values{"month_date": {monthDate}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var out HomepageResponse
+	env, err := c.do(req, &out)
+	if err != nil {
+		return nil, err
+	}
+	out.Raw = env.Data
+	return &out, nil
+}
+
+// SignInRequest 是签到接口的请求参数，经纬度为明文，由客户端负责加密。
+type SignInRequest struct {
+	Address     string
+	AddressName string
+	Province    string
+	City        string
+	Latitude    string
+	Longitude   string
+	Remark      string
+	Comment     string
+}
+
+// SignInResponse 是签到接口的返回结果。
+type SignInResponse struct {
+	Message string
+	Data    json.RawMessage
+}
+
+// SignIn 调用 signin_rsa 接口执行签到。
+func (c *Client) SignIn(ctx context.Context, id Identity, r SignInRequest) (*SignInResponse, error) {
+	encryptedLatitude, err := EncryptCoordinate(r.Latitude)
+	if err != nil {
+		return nil, fmt.Errorf("加密纬度失败: %w", err)
+	}
+	encryptedLongitude, err := EncryptCoordinate(r.Longitude)
+	if err != nil {
+		return nil, fmt.Errorf("加密经度失败: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("address", r.Address)
+	form.Set("province", r.Province)
+	form.Set("city", r.City)
+	form.Set("latitude", encryptedLatitude)
+	form.Set("longitude", encryptedLongitude)
+	form.Set("remark", r.Remark)
+	form.Set("comment", r.Comment)
+	form.Set("address_name", r.AddressName)
+	form.Set("change_sign_resource", "0")
+
+	req, err := c.appRequest(ctx, "/signin_rsa", id, nil, form)
+	if err != nil {
+		return nil, err
+	}
+	env, err := c.do(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SignInResponse{Message: env.Message, Data: env.Data}, nil
+}
+
+// EncryptCoordinate 使用签到公钥对经纬度做 RSA PKCS#1 v1.5 加密并进行 base64 编码。
+func EncryptCoordinate(value string) (string, error) {
+	block, _ := pem.Decode([]byte(signPublicKey))
+	if block == nil {
+		return "", errors.New("公钥解码失败")
+	}
+
+	pubInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	pub, ok := pubInterface.(*rsa.PublicKey)
+	if !ok {
+		return "", errors.New("解析公钥类型失败")
+	}
+
+	encryptedData, err := rsa.EncryptPKCS1v15(rand.Reader, pub, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(encryptedData), nil
+}